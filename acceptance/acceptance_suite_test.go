@@ -13,6 +13,7 @@ import (
 
 	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/redact"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
@@ -95,7 +96,7 @@ jobs:
 func SetTestPipeline(pipelineName string, configFilePath string) error {
 	var err error
 	var setOutput []byte
-	setOutput, err = flyCommand.SetPipeline(pipelineName, configFilePath, nil, nil)
+	setOutput, err = flyCommand.SetPipeline(teamName, pipelineName, configFilePath, nil, nil)
 	fmt.Fprintf(GinkgoWriter, "pipeline '%s' set; output:\n\n%s\n", pipelineName, string(setOutput))
 	return err
 }
@@ -177,10 +178,10 @@ var _ = BeforeSuite(func() {
 
 	By("Creating fly connection")
 	l := logger.NewLogger(sanitizer)
-	flyCommand = fly.NewCommand("concourse-pipeline-resource-target", l, inFlyPath)
+	flyCommand = fly.NewCommand("concourse-pipeline-resource-target", l, redact.NewSecrets(), inFlyPath)
 
 	By("Logging in with fly")
-	_, err = flyCommand.Login(target, teamName, username, password, insecure)
+	_, err = flyCommand.Login(target, teamName, fly.Credentials{Username: username, Password: password}, insecure)
 	Expect(err).NotTo(HaveOccurred())
 })
 
@@ -12,6 +12,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/schema"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
@@ -110,7 +111,7 @@ jobs:
 			},
 		}
 
-		pipelinesFileContents := concourse.OutParams{
+		pipelinesFileContents := schema.PipelinesFile{
 			Pipelines: pipelines,
 		}
 
@@ -155,7 +156,7 @@ jobs:
 
 	Describe("Creating pipelines successfully", func() {
 		AfterEach(func() {
-			_, err := flyCommand.DestroyPipeline(pipelineName)
+			_, err := flyCommand.DestroyPipeline(teamName, pipelineName)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -5,28 +5,36 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
 	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/pool"
+	"github.com/concourse/concourse-pipeline-resource/store"
 )
 
 type Command struct {
 	logger      logger.Logger
 	logFilePath string
 	flyCommand  fly.Command
+	archive     store.Store
 }
 
+// NewCommand returns a check command. If archive is not nil, the config of
+// every pipeline which is checked is stored in it.
 func NewCommand(
 	logger logger.Logger,
 	logFilePath string,
 	flyCommand fly.Command,
+	archive store.Store,
 ) *Command {
 	return &Command{
 		logger:      logger,
 		logFilePath: logFilePath,
 		flyCommand:  flyCommand,
+		archive:     archive,
 	}
 }
 
@@ -68,49 +76,119 @@ func (c *Command) Run(input concourse.CheckRequest) (concourse.CheckResponse, er
 		teams[team.Name] = team
 	}
 
-	pipelineVersions := make(map[string]string)
+	teamNames := make([]string, 0, len(teams))
+	for teamName := range teams {
+		teamNames = append(teamNames, teamName)
+	}
+	sort.Strings(teamNames)
+
+	teamRefs := make([][]pipelineRef, len(teamNames))
+
+	err = pool.Run(input.Source.MaxConcurrency, len(teamNames), func(i int) error {
+		teamName := teamNames[i]
+		team := teams[teamName]
 
-	for teamName, team := range teams {
 		c.logger.Debugf("Performing login\n")
 		_, err := c.flyCommand.Login(
 			input.Source.Target,
 			teamName,
-			team.Username,
-			team.Password,
+			fly.TeamCredentials(team),
 			insecure,
 		)
 		if err != nil {
-			return concourse.CheckResponse{}, err
+			return err
 		}
 
 		c.logger.Debugf("Login successful\n")
 
-		pipelines, err := c.flyCommand.Pipelines()
+		pipelines, err := c.flyCommand.Pipelines(teamName)
 		if err != nil {
-			return concourse.CheckResponse{}, err
+			return err
 		}
 		c.logger.Debugf("Found pipelines (%s): %+v\n", teamName, pipelines)
 
+		pipelines, err = team.Pipelines.Filter(pipelines)
+		if err != nil {
+			return err
+		}
+		c.logger.Debugf("Filtered pipelines (%s): %+v\n", teamName, pipelines)
+		c.logger.With(logger.Team(teamName), logger.Operation("check")).Infof("Checking %d pipelines\n", len(pipelines))
+
 		for _, pipelineName := range pipelines {
-			c.logger.Debugf("Getting pipeline: %s\n", pipelineName)
-			outBytes, err := c.flyCommand.GetPipeline(pipelineName)
+			teamRefs[i] = append(teamRefs[i], pipelineRef{
+				teamName:     teamName,
+				pipelineName: pipelineName,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return concourse.CheckResponse{}, err
+	}
+
+	var refs []pipelineRef
+	for _, r := range teamRefs {
+		refs = append(refs, r...)
+	}
+
+	versions := make([]string, len(refs))
+
+	err = pool.Run(input.Source.MaxConcurrency, len(refs), func(i int) error {
+		c.logger.Debugf("Getting pipeline: %s/%s\n", refs[i].teamName, refs[i].pipelineName)
+		outBytes, err := c.flyCommand.GetPipeline(refs[i].teamName, refs[i].pipelineName)
+		if err != nil {
+			return err
+		}
+
+		versions[i] = fmt.Sprintf(
+			"%x",
+			md5.Sum(outBytes),
+		)
+
+		if c.archive != nil {
+			err = c.archiveConfig(refs[i], versions[i], outBytes)
 			if err != nil {
-				return concourse.CheckResponse{}, err
+				return err
 			}
-
-			version := fmt.Sprintf(
-				"%x",
-				md5.Sum(outBytes),
-			)
-			pipelineVersions[pipelineName] = version
 		}
+
+		return nil
+	})
+	if err != nil {
+		return concourse.CheckResponse{}, err
+	}
+
+	pipelineVersions := make(map[string]string)
+	for i, ref := range refs {
+		pipelineVersions[concourse.VersionKey(ref.teamName, ref.pipelineName)] = versions[i]
 	}
 
 	out := concourse.CheckResponse{
-		pipelineVersions,
+		concourse.MigrateVersion(input.Version, pipelineVersions),
 	}
 
 	c.logger.Debugf("Returning output: %+v\n", out)
 
 	return out, nil
 }
+
+// archiveConfig stores the config of a pipeline unless its version is
+// already stored, as check sees the same configs on every run.
+func (c *Command) archiveConfig(ref pipelineRef, version string, config []byte) error {
+	_, err := c.archive.Get(ref.teamName, ref.pipelineName, version)
+	if err == nil {
+		return nil
+	}
+	if err != store.ErrNotFound {
+		return err
+	}
+
+	c.logger.Debugf("Archiving pipeline: %s/%s\n", ref.teamName, ref.pipelineName)
+	return c.archive.Put(ref.teamName, ref.pipelineName, config)
+}
+
+type pipelineRef struct {
+	teamName     string
+	pipelineName string
+}
@@ -9,8 +9,11 @@ import (
 
 	"github.com/concourse/concourse-pipeline-resource/check"
 	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/fly/flyfakes"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/store"
+	"github.com/concourse/concourse-pipeline-resource/store/storefakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/robdimsdale/sanitizer"
@@ -52,7 +55,7 @@ pipeline1: foo
 pipeline2: foo
 `
 
-		fakeFlyCommand.GetPipelineStub = func(name string) ([]byte, error) {
+		fakeFlyCommand.GetPipelineStub = func(teamName string, name string) ([]byte, error) {
 			ginkgoLogger.Debugf("GetPipelineStub for: %s\n", name)
 
 			switch name {
@@ -97,8 +100,8 @@ pipeline2: foo
 
 		expectedResponse = []concourse.Version{
 			{
-				pipelines[0]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
-				pipelines[1]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[1]))),
+				"main/" + pipelines[0]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
+				"main/" + pipelines[1]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[1]))),
 			},
 		}
 
@@ -106,6 +109,7 @@ pipeline2: foo
 			ginkgoLogger,
 			logFilePath,
 			fakeFlyCommand,
+			nil,
 		)
 	})
 
@@ -125,11 +129,82 @@ pipeline2: foo
 		Expect(response).To(Equal(expectedResponse))
 	})
 
+	Context("when an archive is configured", func() {
+		var fakeArchive *storefakes.FakeStore
+
+		BeforeEach(func() {
+			fakeArchive = &storefakes.FakeStore{}
+			fakeArchive.GetReturns(nil, store.ErrNotFound)
+			command = check.NewCommand(ginkgoLogger, logFilePath, fakeFlyCommand, fakeArchive)
+		})
+
+		It("stores the config of each pipeline", func() {
+			_, err := command.Run(checkRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeArchive.GetCallCount()).To(Equal(2))
+			team, name, version := fakeArchive.GetArgsForCall(0)
+			Expect(team).To(Equal("main"))
+			Expect(name).To(Equal(pipelines[0]))
+			Expect(version).To(Equal(fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0])))))
+
+			Expect(fakeArchive.PutCallCount()).To(Equal(2))
+			team, name, config := fakeArchive.PutArgsForCall(0)
+			Expect(team).To(Equal("main"))
+			Expect(name).To(Equal(pipelines[0]))
+			Expect(string(config)).To(Equal(pipelineContents[0]))
+		})
+
+		Context("when the config of a pipeline is already stored", func() {
+			BeforeEach(func() {
+				fakeArchive.GetStub = func(teamName string, pipelineName string, version string) ([]byte, error) {
+					if pipelineName == pipelines[0] {
+						return []byte(pipelineContents[0]), nil
+					}
+					return nil, store.ErrNotFound
+				}
+			})
+
+			It("only stores the others", func() {
+				_, err := command.Run(checkRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeArchive.PutCallCount()).To(Equal(1))
+				_, name, _ := fakeArchive.PutArgsForCall(0)
+				Expect(name).To(Equal(pipelines[1]))
+			})
+		})
+
+		Context("when looking up a config returns an error", func() {
+			BeforeEach(func() {
+				fakeArchive.GetReturns(nil, fmt.Errorf("archive unavailable"))
+			})
+
+			It("returns the error", func() {
+				_, err := command.Run(checkRequest)
+				Expect(err).To(MatchError("archive unavailable"))
+
+				Expect(fakeArchive.PutCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when storing a config returns an error", func() {
+			BeforeEach(func() {
+				fakeArchive.PutReturns(fmt.Errorf("archive unavailable"))
+			})
+
+			It("returns the error", func() {
+				_, err := command.Run(checkRequest)
+				Expect(err).To(MatchError("archive unavailable"))
+			})
+		})
+	})
+
 	Context("when the most recent version is provided", func() {
 		BeforeEach(func() {
 			checkRequest.Version = concourse.Version{
-				pipelines[0]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
-				pipelines[1]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[1]))),
+				"main/" + pipelines[0]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
+				"main/" + pipelines[1]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[1]))),
 			}
 		})
 
@@ -141,6 +216,62 @@ pipeline2: foo
 		})
 	})
 
+	Context("when a legacy version keyed only by pipeline name is provided", func() {
+		BeforeEach(func() {
+			checkRequest.Version = concourse.Version{
+				pipelines[0]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
+				pipelines[1]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[1]))),
+			}
+		})
+
+		Context("when no pipeline has changed", func() {
+			It("returns the legacy version so no new version is emitted", func() {
+				response, err := command.Run(checkRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response).To(Equal(concourse.CheckResponse{checkRequest.Version}))
+			})
+		})
+
+		Context("when a pipeline has changed", func() {
+			BeforeEach(func() {
+				checkRequest.Version[pipelines[0]] = "some-old-version"
+			})
+
+			It("returns the team-qualified version", func() {
+				response, err := command.Run(checkRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response).To(Equal(expectedResponse))
+			})
+		})
+	})
+
+	Context("when pipelines with the same name exist in multiple teams", func() {
+		BeforeEach(func() {
+			checkRequest.Source.Teams = append(checkRequest.Source.Teams, concourse.Team{
+				Name: "other-team",
+			})
+
+			fakeFlyCommand.GetPipelineStub = func(teamName string, name string) ([]byte, error) {
+				return []byte(fmt.Sprintf("%s-%s", teamName, name)), nil
+			}
+		})
+
+		It("returns a version for the pipeline in each team", func() {
+			response, err := command.Run(checkRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(2))
+
+			Expect(response).To(HaveLen(1))
+			Expect(response[0]).To(HaveLen(4))
+			Expect(response[0]).To(HaveKey("main/" + pipelines[0]))
+			Expect(response[0]).To(HaveKey("other-team/" + pipelines[0]))
+			Expect(response[0]["main/"+pipelines[0]]).NotTo(Equal(response[0]["other-team/"+pipelines[0]]))
+		})
+	})
+
 	Context("when some other version is provided", func() {
 		BeforeEach(func() {
 			checkRequest.Version = concourse.Version{
@@ -153,6 +284,72 @@ pipeline2: foo
 		})
 	})
 
+	Context("when the team filters pipelines", func() {
+		BeforeEach(func() {
+			checkRequest.Source.Teams[0].Pipelines = concourse.PipelineFilter{
+				Include: []string{"pipeline *"},
+				Exclude: []string{"/2$/"},
+			}
+		})
+
+		It("only returns versions of matching pipelines", func() {
+			response, err := command.Run(checkRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response).To(Equal(concourse.CheckResponse{
+				{
+					"main/" + pipelines[0]: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
+				},
+			}))
+
+			Expect(fakeFlyCommand.GetPipelineCallCount()).To(Equal(1))
+		})
+
+		Context("when pipelines are instanced", func() {
+			BeforeEach(func() {
+				pipelines = []string{"pipeline 1/branch:main", "pipeline 2/branch:main"}
+
+				fakeFlyCommand.GetPipelineReturns([]byte(pipelineContents[0]), nil)
+			})
+
+			It("matches the names of the pipelines", func() {
+				response, err := command.Run(checkRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response).To(Equal(concourse.CheckResponse{
+					{
+						"main/pipeline 1/branch:main": fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
+					},
+				}))
+			})
+		})
+
+		Context("when a pattern is invalid", func() {
+			BeforeEach(func() {
+				checkRequest.Source.Teams[0].Pipelines.Include = []string{"["}
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(checkRequest)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Context("when max concurrency is set", func() {
+		BeforeEach(func() {
+			checkRequest.Source.MaxConcurrency = 2
+		})
+
+		It("returns pipelines checksum without error", func() {
+			response, err := command.Run(checkRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response).To(Equal(expectedResponse))
+			Expect(fakeFlyCommand.GetPipelineCallCount()).To(Equal(2))
+		})
+	})
+
 	Context("when log files already exist", func() {
 		var (
 			otherFilePath1 string
@@ -196,12 +393,30 @@ pipeline2: foo
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(1))
-			_, _, _, _, insecure := fakeFlyCommand.LoginArgsForCall(0)
+			_, _, _, insecure := fakeFlyCommand.LoginArgsForCall(0)
 
 			Expect(insecure).To(BeTrue())
 		})
 	})
 
+	Context("when a team authenticates with a token", func() {
+		BeforeEach(func() {
+			checkRequest.Source.Teams[0].Username = ""
+			checkRequest.Source.Teams[0].Password = ""
+			checkRequest.Source.Teams[0].Token = "some token"
+		})
+
+		It("invokes the login with the token", func() {
+			_, err := command.Run(checkRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(1))
+			_, _, credentials, _ := fakeFlyCommand.LoginArgsForCall(0)
+
+			Expect(credentials).To(Equal(fly.Credentials{Token: "some token"}))
+		})
+	})
+
 	Context("when insecure fails to parse into a boolean", func() {
 		BeforeEach(func() {
 			checkRequest.Source.Insecure = "unparsable"
@@ -12,8 +12,10 @@ import (
 	"github.com/concourse/concourse-pipeline-resource/concourse"
 	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	"github.com/concourse/concourse-pipeline-resource/schema"
+	"github.com/concourse/concourse-pipeline-resource/store"
 	"github.com/concourse/concourse-pipeline-resource/validator"
-	"github.com/robdimsdale/sanitizer"
 )
 
 const (
@@ -26,6 +28,15 @@ var (
 )
 
 func main() {
+	err := run()
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+// run performs the request, returning any error once the logger is set up.
+// Errors before then are fatal.
+func run() error {
 	checkDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
 	if err != nil {
 		log.Fatalln(err)
@@ -40,16 +51,32 @@ func main() {
 
 	fmt.Fprintf(os.Stderr, "Logging to %s\n", logFile.Name())
 
-	err = json.NewDecoder(os.Stdin).Decode(&input)
+	stdin, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
 		fmt.Fprintf(logFile, "Exiting with error: %v\n", err)
 		log.Fatalln(err)
 	}
 
-	sanitized := concourse.SanitizedSource(input.Source)
-	sanitizer := sanitizer.NewSanitizer(sanitized, logFile)
+	err = json.Unmarshal(stdin, &input)
+	if err != nil {
+		fmt.Fprintf(logFile, "Exiting with error: %v\n", err)
+		log.Fatalln(err)
+	}
+
+	secrets := redact.NewSecrets()
+	secrets.AddAll(concourse.SanitizedSource(input.Source))
+
+	log.SetOutput(redact.NewWriter(secrets, os.Stderr))
 
-	l = logger.NewLogger(sanitizer)
+	// An unknown log level or format is reported by the validator below.
+	logLevel, _ := logger.ParseLevel(input.Source.LogLevel)
+	logFormat, _ := logger.ParseFormat(input.Source.LogFormat)
+
+	l = logger.New(redact.NewWriter(secrets, logFile), logger.Options{
+		Level:  logLevel,
+		Format: logFormat,
+		Tee:    redact.NewWriter(secrets, os.Stderr),
+	})
 
 	flyBinaryPath := filepath.Join(checkDir, flyBinaryName)
 
@@ -57,24 +84,53 @@ func main() {
 		input.Source.Target = os.Getenv(atcExternalURLEnvKey)
 	}
 
-	flyCommand := fly.NewCommand(input.Source.Target, l, flyBinaryPath)
+	var flyCommand fly.Command
+	if input.Source.Client == concourse.ClientAPI {
+		flyCommand = fly.NewAPICommand(l, secrets)
+	} else {
+		flyCommand = fly.NewCommand(input.Source.Target, l, secrets, flyBinaryPath)
+	}
+
+	// The sessions hold the tokens of the teams, so are removed however the
+	// request ends.
+	defer flyCommand.Cleanup()
+
+	if input.Source.ValidateSchema {
+		err = validator.ValidateSchema(stdin, "", schema.Of(input))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !input.Source.AllowUnknownFields {
+		err = validator.ValidateJSONFields(stdin, input)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = validator.ValidateCheck(input)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
 
-	command := check.NewCommand(l, logFile.Name(), flyCommand)
-	response, err := command.Run(input)
+	archive, err := store.New(input.Source.Archive)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
 
-	err = json.NewEncoder(os.Stdout).Encode(response)
+	command := check.NewCommand(l, logFile.Name(), flyCommand, archive)
+	response, err := command.Run(input)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
+
+	return json.NewEncoder(os.Stdout).Encode(response)
+}
+
+// exitWithError logs err, which is also written to stderr by the logger, and
+// exits.
+func exitWithError(err error) {
+	l.Errorf("Exiting with error: %v\n", err)
+	os.Exit(1)
 }
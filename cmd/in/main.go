@@ -12,8 +12,10 @@ import (
 	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/in"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	"github.com/concourse/concourse-pipeline-resource/schema"
+	"github.com/concourse/concourse-pipeline-resource/store"
 	"github.com/concourse/concourse-pipeline-resource/validator"
-	"github.com/robdimsdale/sanitizer"
 )
 
 const (
@@ -26,6 +28,15 @@ var (
 )
 
 func main() {
+	err := run()
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+// run performs the request, returning any error once the logger is set up.
+// Errors before then are fatal.
+func run() error {
 	if len(os.Args) < 2 {
 		log.Fatalln(fmt.Sprintf(
 			"not enough args - usage: %s <sources directory>", os.Args[0]))
@@ -47,16 +58,32 @@ func main() {
 
 	fmt.Fprintf(os.Stderr, "Logging to %s\n", logFile.Name())
 
-	err = json.NewDecoder(os.Stdin).Decode(&input)
+	stdin, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(logFile, "Exiting with error: %v\n", err)
+		log.Fatalln(err)
+	}
+
+	err = json.Unmarshal(stdin, &input)
 	if err != nil {
 		fmt.Fprintf(logFile, "Exiting with error: %v\n", err)
 		log.Fatalln(err)
 	}
 
-	sanitized := concourse.SanitizedSource(input.Source)
-	sanitizer := sanitizer.NewSanitizer(sanitized, logFile)
+	secrets := redact.NewSecrets()
+	secrets.AddAll(concourse.SanitizedSource(input.Source))
+
+	log.SetOutput(redact.NewWriter(secrets, os.Stderr))
+
+	// An unknown log level or format is reported by the validator below.
+	logLevel, _ := logger.ParseLevel(input.Source.LogLevel)
+	logFormat, _ := logger.ParseFormat(input.Source.LogFormat)
 
-	l = logger.NewLogger(sanitizer)
+	l = logger.New(redact.NewWriter(secrets, logFile), logger.Options{
+		Level:  logLevel,
+		Format: logFormat,
+		Tee:    redact.NewWriter(secrets, os.Stderr),
+	})
 
 	flyBinaryPath := filepath.Join(inDir, flyBinaryName)
 
@@ -64,25 +91,54 @@ func main() {
 		input.Source.Target = os.Getenv(atcExternalURLEnvKey)
 	}
 
-	flyCommand := fly.NewCommand(input.Source.Target, l, flyBinaryPath)
+	var flyCommand fly.Command
+	if input.Source.Client == concourse.ClientAPI {
+		flyCommand = fly.NewAPICommand(l, secrets)
+	} else {
+		flyCommand = fly.NewCommand(input.Source.Target, l, secrets, flyBinaryPath)
+	}
+
+	// The sessions hold the tokens of the teams, so are removed however the
+	// request ends.
+	defer flyCommand.Cleanup()
+
+	if input.Source.ValidateSchema {
+		err = validator.ValidateSchema(stdin, "", schema.Of(input))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !input.Source.AllowUnknownFields {
+		err = validator.ValidateJSONFields(stdin, input)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = validator.ValidateIn(input)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
 
-	response, err := in.NewCommand(l, flyCommand, downloadDir).Run(input)
+	archive, err := store.New(input.Source.Archive)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
 
-	l.Debugf("Returning output: %+v\n", response)
-
-	err = json.NewEncoder(os.Stdout).Encode(response)
+	response, err := in.NewCommand(l, flyCommand, archive, downloadDir).Run(input)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
+
+	l.Debugf("Returning output: %+v\n", response)
+
+	return json.NewEncoder(os.Stdout).Encode(response)
+}
+
+// exitWithError logs err, which is also written to stderr by the logger, and
+// exits.
+func exitWithError(err error) {
+	l.Errorf("Exiting with error: %v\n", err)
+	os.Exit(1)
 }
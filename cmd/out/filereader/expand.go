@@ -0,0 +1,245 @@
+package filereader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+const defaultNameTemplate = "{{.Base}}"
+
+// PathTemplateData is available to the name and team templates of a
+// pipeline with a config_glob, e.g. `{{index .Segments 1}}` is `main` for
+// the config `pipelines/main/deploy.yml`.
+type PathTemplateData struct {
+	// Path is the path of the config, relative to the sources directory.
+	Path string
+
+	// Dir is the directory containing the config.
+	Dir string
+
+	// Base is the file name of the config without its extension.
+	Base string
+
+	// Segments are the elements of Path.
+	Segments []string
+}
+
+// ExpandPipelines replaces each pipeline with a config_glob by a pipeline
+// per matching config. Other pipelines are returned unchanged.
+func ExpandPipelines(pipelines []concourse.Pipeline, sourcesDir string) ([]concourse.Pipeline, error) {
+	expanded := []concourse.Pipeline{}
+
+	for i, p := range pipelines {
+		if p.ConfigGlob == "" {
+			expanded = append(expanded, p)
+			continue
+		}
+
+		globbed, err := expandPipeline(p, sourcesDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand config_glob for pipeline[%d]: %v", i, err)
+		}
+
+		expanded = append(expanded, globbed...)
+	}
+
+	return expanded, nil
+}
+
+func expandPipeline(p concourse.Pipeline, sourcesDir string) ([]concourse.Pipeline, error) {
+	nameTemplate := p.Name
+	if nameTemplate == "" {
+		nameTemplate = defaultNameTemplate
+	}
+
+	name, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := template.New("team").Option("missingkey=error").Parse(p.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	root, configs, err := matchConfigs(p.ConfigGlob, sourcesDir, p.DirVarsFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no pipeline configs match %s", p.ConfigGlob)
+	}
+
+	var pipelines []concourse.Pipeline
+	for _, config := range configs {
+		data := PathTemplateData{
+			Path:     config,
+			Dir:      path.Dir(config),
+			Base:     strings.TrimSuffix(path.Base(config), path.Ext(config)),
+			Segments: strings.Split(config, "/"),
+		}
+
+		pipeline := p
+		pipeline.ConfigGlob = ""
+		pipeline.DirVarsFiles = nil
+		pipeline.ConfigFile = config
+
+		pipeline.Name, err = execute(name, data)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline.TeamName, err = execute(team, data)
+		if err != nil {
+			return nil, err
+		}
+
+		dirVarsFiles, err := findDirVarsFiles(root, path.Dir(config), sourcesDir, p.DirVarsFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(dirVarsFiles) > 0 {
+			// Shared vars files come first so that the pipeline's own vars
+			// files override them.
+			pipeline.VarsFiles = append(dirVarsFiles, p.VarsFiles...)
+		}
+
+		pipelines = append(pipelines, pipeline)
+	}
+
+	return pipelines, nil
+}
+
+// matchConfigs returns the configs matching glob, or every YAML file under
+// glob if it is a directory, as slash-separated paths relative to
+// sourcesDir, along with the directory the matches are rooted at. Files
+// named like one of the dirVarsFiles are never configs.
+func matchConfigs(glob string, sourcesDir string, dirVarsFiles []string) (string, []string, error) {
+	fullGlob := filepath.Join(sourcesDir, filepath.FromSlash(glob))
+
+	var matches []string
+
+	info, err := os.Stat(fullGlob)
+	if err == nil && info.IsDir() {
+		err = filepath.Walk(fullGlob, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && isYAML(p) {
+				matches = append(matches, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	} else {
+		matches, err = filepath.Glob(fullGlob)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	var configs []string
+	for _, m := range matches {
+		if isDirVarsFile(filepath.Base(m), dirVarsFiles) {
+			continue
+		}
+
+		if info, err := os.Stat(m); err != nil || info.IsDir() {
+			continue
+		}
+
+		rel, err := filepath.Rel(sourcesDir, m)
+		if err != nil {
+			return "", nil, err
+		}
+
+		configs = append(configs, filepath.ToSlash(rel))
+	}
+
+	sort.Strings(configs)
+
+	return globRoot(glob), configs, nil
+}
+
+// globRoot returns the longest leading directory of glob which contains no
+// pattern characters.
+func globRoot(glob string) string {
+	var root []string
+	for _, segment := range strings.Split(path.Clean(glob), "/") {
+		if strings.ContainsAny(segment, `*?[\`) {
+			return path.Join(root...)
+		}
+		root = append(root, segment)
+	}
+
+	// The glob is a directory, or a single file.
+	return path.Clean(glob)
+}
+
+// findDirVarsFiles returns the dirVarsFiles which exist in dir or one of its
+// parents up to and including root, outermost first.
+func findDirVarsFiles(root string, dir string, sourcesDir string, dirVarsFiles []string) ([]string, error) {
+	var dirs []string
+	for d := dir; ; d = path.Dir(d) {
+		dirs = append([]string{d}, dirs...)
+
+		if d == root || d == "." || d == "/" || !strings.HasPrefix(d, root) {
+			break
+		}
+	}
+
+	var found []string
+	for _, d := range dirs {
+		for _, name := range dirVarsFiles {
+			candidate := path.Join(d, name)
+
+			_, err := os.Stat(filepath.Join(sourcesDir, filepath.FromSlash(candidate)))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			found = append(found, candidate)
+		}
+	}
+
+	return found, nil
+}
+
+func isYAML(p string) bool {
+	ext := filepath.Ext(p)
+	return ext == ".yml" || ext == ".yaml"
+}
+
+func isDirVarsFile(name string, dirVarsFiles []string) bool {
+	for _, v := range dirVarsFiles {
+		if path.Base(v) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func execute(t *template.Template, data PathTemplateData) (string, error) {
+	var b bytes.Buffer
+	err := t.Execute(&b, data)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,146 @@
+package filereader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/cmd/out/filereader"
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExpandPipelines", func() {
+	var (
+		sourcesDir string
+		pipelines  []concourse.Pipeline
+	)
+
+	writeFile := func(relativePath string) {
+		fullPath := filepath.Join(sourcesDir, relativePath)
+
+		err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(fullPath, []byte("---\n"), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		sourcesDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		writeFile("repo/pipelines/vars.yml")
+		writeFile("repo/pipelines/main/deploy.yml")
+		writeFile("repo/pipelines/main/test.yml")
+		writeFile("repo/pipelines/other/vars.yml")
+		writeFile("repo/pipelines/other/build.yaml")
+		writeFile("repo/pipelines/other/README.md")
+
+		pipelines = []concourse.Pipeline{
+			{
+				Name:       "static",
+				ConfigFile: "repo/static.yml",
+				TeamName:   "main",
+			},
+			{
+				ConfigGlob:   "repo/pipelines/*/*.y*ml",
+				TeamName:     "{{index .Segments 2}}",
+				VarsFiles:    []string{"repo/common.yml"},
+				DirVarsFiles: []string{"vars.yml"},
+				Unpaused:     true,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(sourcesDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("expands a glob into a pipeline per config", func() {
+		expanded, err := filereader.ExpandPipelines(pipelines, sourcesDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(expanded).To(Equal([]concourse.Pipeline{
+			pipelines[0],
+			{
+				Name:       "deploy",
+				ConfigFile: "repo/pipelines/main/deploy.yml",
+				TeamName:   "main",
+				VarsFiles:  []string{"repo/pipelines/vars.yml", "repo/common.yml"},
+				Unpaused:   true,
+			},
+			{
+				Name:       "test",
+				ConfigFile: "repo/pipelines/main/test.yml",
+				TeamName:   "main",
+				VarsFiles:  []string{"repo/pipelines/vars.yml", "repo/common.yml"},
+				Unpaused:   true,
+			},
+			{
+				Name:       "build",
+				ConfigFile: "repo/pipelines/other/build.yaml",
+				TeamName:   "other",
+				VarsFiles:  []string{"repo/pipelines/vars.yml", "repo/pipelines/other/vars.yml", "repo/common.yml"},
+				Unpaused:   true,
+			},
+		}))
+	})
+
+	Context("when the name is a template", func() {
+		BeforeEach(func() {
+			pipelines[1].Name = "{{index .Segments 2}}-{{.Base}}"
+		})
+
+		It("derives the name from the path", func() {
+			expanded, err := filereader.ExpandPipelines(pipelines, sourcesDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(expanded[1].Name).To(Equal("main-deploy"))
+			Expect(expanded[3].Name).To(Equal("other-build"))
+		})
+	})
+
+	Context("when the glob is a directory", func() {
+		BeforeEach(func() {
+			pipelines[1].ConfigGlob = "repo/pipelines/other"
+			pipelines[1].TeamName = "other"
+		})
+
+		It("expands every YAML file under it", func() {
+			expanded, err := filereader.ExpandPipelines(pipelines, sourcesDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(expanded).To(HaveLen(2))
+			Expect(expanded[1].ConfigFile).To(Equal("repo/pipelines/other/build.yaml"))
+			Expect(expanded[1].VarsFiles).To(Equal([]string{"repo/pipelines/other/vars.yml", "repo/common.yml"}))
+		})
+	})
+
+	Context("when the glob matches nothing", func() {
+		BeforeEach(func() {
+			pipelines[1].ConfigGlob = "repo/missing/*.yml"
+		})
+
+		It("returns an error", func() {
+			_, err := filereader.ExpandPipelines(pipelines, sourcesDir)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(ContainSubstring("pipeline[1]"))
+		})
+	})
+
+	Context("when a template refers to a missing segment", func() {
+		BeforeEach(func() {
+			pipelines[1].TeamName = "{{index .Segments 9}}"
+		})
+
+		It("returns an error", func() {
+			_, err := filereader.ExpandPipelines(pipelines, sourcesDir)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
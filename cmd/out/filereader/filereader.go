@@ -6,10 +6,16 @@ import (
 	"path/filepath"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/schema"
+	"github.com/concourse/concourse-pipeline-resource/validator"
 	"gopkg.in/yaml.v2"
 )
 
-func PipelinesFromFile(pipelinesFilename string, sourcesDir string) ([]concourse.Pipeline, error) {
+// PipelinesFromFile reads the pipelines from the pipelines file. Unless
+// allowUnknownFields is true, keys which do not correspond to a field of a
+// pipeline are an error rather than silently ignored. If validateSchema is
+// true, the file must also conform to the published pipelines file schema.
+func PipelinesFromFile(pipelinesFilename string, sourcesDir string, allowUnknownFields bool, validateSchema bool) ([]concourse.Pipeline, error) {
 	if pipelinesFilename != "" {
 		if sourcesDir == "" {
 			return nil, fmt.Errorf("sourcesDir must be non-empty")
@@ -20,12 +26,26 @@ func PipelinesFromFile(pipelinesFilename string, sourcesDir string) ([]concourse
 			return nil, err
 		}
 
-		var fileContents concourse.OutParams
+		if validateSchema {
+			err = validator.ValidateYAMLSchema(b, pipelinesFilename, schema.PipelinesFileSchema())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var fileContents schema.PipelinesFile
 		err = yaml.Unmarshal(b, &fileContents)
 		if err != nil {
 			return nil, err
 		}
 
+		if !allowUnknownFields {
+			err = validator.ValidateYAMLFields(b, pipelinesFilename, fileContents)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		return fileContents.Pipelines, nil
 	}
 
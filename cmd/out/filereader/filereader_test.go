@@ -7,6 +7,7 @@ import (
 
 	"github.com/concourse/concourse-pipeline-resource/cmd/out/filereader"
 	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/schema"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"gopkg.in/yaml.v2"
@@ -45,7 +46,7 @@ var _ = Describe("Filereader", func() {
 			},
 		}
 
-		pipelinesFileContents := concourse.OutParams{
+		pipelinesFileContents := schema.PipelinesFile{
 			Pipelines: pipelines,
 		}
 
@@ -66,7 +67,7 @@ var _ = Describe("Filereader", func() {
 	})
 
 	It("parses pipelines from the file", func() {
-		returnedPipelines, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir)
+		returnedPipelines, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, false)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(returnedPipelines).To(Equal(pipelines))
@@ -78,7 +79,7 @@ var _ = Describe("Filereader", func() {
 		})
 
 		It("returns error", func() {
-			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir)
+			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, false)
 			Expect(err).To(HaveOccurred())
 		})
 	})
@@ -89,7 +90,7 @@ var _ = Describe("Filereader", func() {
 		})
 
 		It("returns error", func() {
-			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir)
+			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, false)
 			Expect(err).To(HaveOccurred())
 		})
 	})
@@ -109,18 +110,109 @@ var _ = Describe("Filereader", func() {
 		})
 
 		It("returns error", func() {
-			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir)
+			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, false)
 			Expect(err).To(HaveOccurred())
 		})
 	})
 
+	Context("when the pipelines file has unknown fields", func() {
+		BeforeEach(func() {
+			pipelinesFileContentsBytes := []byte(`
+pipelines:
+- name: name-1
+  config_file: pipeline_1.yml
+  var_files:
+  - vars_1.yml
+`)
+
+			err := ioutil.WriteFile(
+				filepath.Join(sourcesDir, pipelinesFilename),
+				pipelinesFileContentsBytes,
+				os.ModePerm,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns error with a suggestion", func() {
+			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, false)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(ContainSubstring("pipelines[0].var_files: unknown field var_files, did you mean vars_files?"))
+		})
+
+		Context("when unknown fields are allowed", func() {
+			It("ignores them", func() {
+				returnedPipelines, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, true, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(returnedPipelines).To(HaveLen(1))
+				Expect(returnedPipelines[0].VarsFiles).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the pipelines file has params other than pipelines", func() {
+		BeforeEach(func() {
+			pipelinesFileContentsBytes := []byte(`
+pipelines:
+- name: name-1
+  team: main
+  config_file: pipeline_1.yml
+dry_run: true
+concurrency: 2
+`)
+
+			err := ioutil.WriteFile(
+				filepath.Join(sourcesDir, pipelinesFilename),
+				pipelinesFileContentsBytes,
+				os.ModePerm,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error for each of them, as they would be ignored", func() {
+			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, false)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(ContainSubstring("concurrency: unknown field concurrency"))
+			Expect(err.Error()).To(ContainSubstring("dry_run: unknown field dry_run"))
+			Expect(err.Error()).NotTo(ContainSubstring("did you mean"))
+		})
+	})
+
+	Context("when the pipelines file does not conform to the schema", func() {
+		BeforeEach(func() {
+			pipelinesFileContentsBytes := []byte(`
+pipelines:
+- name: name-1
+  team: main
+  config_file: pipeline_1.yml
+  vars_files: vars_1.yml
+`)
+
+			err := ioutil.WriteFile(
+				filepath.Join(sourcesDir, pipelinesFilename),
+				pipelinesFileContentsBytes,
+				os.ModePerm,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns error with the location of the problem", func() {
+			_, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, true)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(ContainSubstring("pipelines[0].vars_files: must be of type array"))
+		})
+	})
+
 	Context("when pipelines filename is empty", func() {
 		BeforeEach(func() {
 			pipelinesFilename = ""
 		})
 
 		It("returns nil pipelines without error", func() {
-			returnedPipelines, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir)
+			returnedPipelines, err := filereader.PipelinesFromFile(pipelinesFilename, sourcesDir, false, false)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(returnedPipelines).NotTo(BeNil())
@@ -13,8 +13,10 @@ import (
 	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/logger"
 	"github.com/concourse/concourse-pipeline-resource/out"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	"github.com/concourse/concourse-pipeline-resource/schema"
+	"github.com/concourse/concourse-pipeline-resource/store"
 	"github.com/concourse/concourse-pipeline-resource/validator"
-	"github.com/robdimsdale/sanitizer"
 )
 
 const (
@@ -27,6 +29,15 @@ var (
 )
 
 func main() {
+	err := run()
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+// run performs the request, returning any error once the logger is set up.
+// Errors before then are fatal.
+func run() error {
 	if len(os.Args) < 2 {
 		log.Fatalln(fmt.Sprintf(
 			"not enough args - usage: %s <sources directory>", os.Args[0]))
@@ -48,16 +59,33 @@ func main() {
 
 	fmt.Fprintf(os.Stderr, "Logging to %s\n", logFile.Name())
 
-	err = json.NewDecoder(os.Stdin).Decode(&input)
+	stdin, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(logFile, "Exiting with error: %v\n", err)
+		log.Fatalln(err)
+	}
+
+	err = json.Unmarshal(stdin, &input)
 	if err != nil {
 		fmt.Fprintf(logFile, "Exiting with error: %v\n", err)
 		log.Fatalln(err)
 	}
 
-	sanitized := concourse.SanitizedSource(input.Source)
-	sanitizer := sanitizer.NewSanitizer(sanitized, logFile)
+	secrets := redact.NewSecrets()
+	secrets.AddAll(concourse.SanitizedSource(input.Source))
 
-	l = logger.NewLogger(sanitizer)
+	stderr := redact.NewWriter(secrets, os.Stderr)
+	log.SetOutput(stderr)
+
+	// An unknown log level or format is reported by the validator below.
+	logLevel, _ := logger.ParseLevel(input.Source.LogLevel)
+	logFormat, _ := logger.ParseFormat(input.Source.LogFormat)
+
+	l = logger.New(redact.NewWriter(secrets, logFile), logger.Options{
+		Level:  logLevel,
+		Format: logFormat,
+		Tee:    stderr,
+	})
 
 	flyBinaryPath := filepath.Join(outDir, flyBinaryName)
 
@@ -65,43 +93,84 @@ func main() {
 		input.Source.Target = os.Getenv(atcExternalURLEnvKey)
 	}
 
-	flyCommand := fly.NewCommand(input.Source.Target, l, flyBinaryPath)
+	var flyCommand fly.Command
+	if input.Source.Client == concourse.ClientAPI {
+		flyCommand = fly.NewAPICommand(l, secrets)
+	} else {
+		flyCommand = fly.NewCommand(input.Source.Target, l, secrets, flyBinaryPath)
+	}
+
+	// The sessions hold the tokens of the teams, so are removed however the
+	// request ends.
+	defer flyCommand.Cleanup()
+
+	if input.Source.ValidateSchema {
+		err = validator.ValidateSchema(stdin, "", schema.Of(input))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !input.Source.AllowUnknownFields {
+		err = validator.ValidateJSONFields(stdin, input)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = validator.ValidateOut(input)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
 
 	if input.Params.PipelinesFile != "" {
-		pipelinesFromFile, err := filereader.PipelinesFromFile(input.Params.PipelinesFile, sourcesDir)
+		pipelinesFromFile, err := filereader.PipelinesFromFile(input.Params.PipelinesFile, sourcesDir, input.Source.AllowUnknownFields, input.Source.ValidateSchema)
 		if err != nil {
-			l.Debugf("Exiting with error: %v\n", err)
-			log.Fatalln(err)
+			return err
 		}
 
 		input.Params.PipelinesFile = ""
 		input.Params.Pipelines = pipelinesFromFile
 	}
 
-	// Validate contents of pipelines file
-	err = validator.ValidateOut(input)
+	input.Params.Pipelines, err = filereader.ExpandPipelines(input.Params.Pipelines, sourcesDir)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
 
-	response, err := out.NewCommand(l, flyCommand, sourcesDir).Run(input)
+	// Validate contents of pipelines file, and expanded pipelines
+	err = validator.ValidateOut(input)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		return err
 	}
 
-	l.Debugf("Returning output: %+v\n", response)
+	archive, err := store.New(input.Source.Archive)
+	if err != nil {
+		return err
+	}
 
-	err = json.NewEncoder(os.Stdout).Encode(response)
+	response, err := out.NewCommand(l, flyCommand, archive, sourcesDir, stderr, secrets).Run(input)
 	if err != nil {
-		l.Debugf("Exiting with error: %v\n", err)
-		log.Fatalln(err)
+		if len(response.Version) > 0 || len(response.Metadata) > 0 {
+			// Concourse discards the output of a failed step, so the version
+			// and metadata of the pipelines which were set are written to
+			// stderr instead, on a single line which can be parsed.
+			b, marshalErr := json.Marshal(response)
+			if marshalErr == nil {
+				fmt.Fprintf(stderr, "partial response: %s\n", b)
+			}
+		}
+		return err
 	}
+
+	l.Debugf("Returning output: %+v\n", response)
+
+	return json.NewEncoder(os.Stdout).Encode(response)
+}
+
+// exitWithError logs err, which is also written to stderr by the logger, and
+// exits.
+func exitWithError(err error) {
+	l.Errorf("Exiting with error: %v\n", err)
+	os.Exit(1)
 }
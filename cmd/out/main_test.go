@@ -0,0 +1,130 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+// fakeFly succeeds for every command except setting the pipeline named
+// failing-pipeline.
+const fakeFly = `#!/bin/sh
+case "$*" in
+  *"set-pipeline"*"-p failing-pipeline "*)
+    echo "failed to set" >&2
+    exit 1
+    ;;
+  *"pipelines --json"*)
+    echo "[]"
+    ;;
+  *"get-pipeline"*)
+    echo "jobs: []"
+    ;;
+esac
+`
+
+var _ = Describe("out", func() {
+	var (
+		sourcesDir string
+		request    concourse.OutRequest
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourcesDir, err = ioutil.TempDir("", "out-sources")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(sourcesDir, "pipeline.yml"), []byte("jobs: []\n"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		// fly is expected next to the out binary.
+		err = ioutil.WriteFile(filepath.Join(filepath.Dir(outPath), "fly"), []byte(fakeFly), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		failFast := false
+		request = concourse.OutRequest{
+			Source: concourse.Source{
+				Target: "https://concourse.example.com",
+				Teams: []concourse.Team{
+					{Name: "main", Username: "some-user", Password: "some-password"},
+				},
+			},
+			Params: concourse.OutParams{
+				Pipelines: []concourse.Pipeline{
+					{Name: "passing-pipeline", TeamName: "main", ConfigFile: "pipeline.yml"},
+					{Name: "failing-pipeline", TeamName: "main", ConfigFile: "pipeline.yml"},
+				},
+				FailFast: &failFast,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(sourcesDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	run := func() *gexec.Session {
+		stdin, err := json.Marshal(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		command := exec.Command(outPath, sourcesDir)
+		command.Stdin = bytes.NewReader(stdin)
+
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session, 30*time.Second).Should(gexec.Exit())
+		return session
+	}
+
+	Context("when a pipeline fails to be set", func() {
+		It("fails, writing the response of the pipelines which were set to stderr", func() {
+			session := run()
+			Expect(session.ExitCode()).To(Equal(1))
+
+			Expect(session.Out.Contents()).To(BeEmpty())
+			Expect(session.Err).To(gbytes.Say("1 of 2 pipelines failed to be set"))
+
+			matches := regexp.MustCompile(`(?m)^partial response: (.*)$`).FindSubmatch(session.Err.Contents())
+			Expect(matches).NotTo(BeNil())
+
+			var response concourse.OutResponse
+			err := json.Unmarshal(matches[1], &response)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Version).To(HaveKey("main/passing-pipeline"))
+			Expect(response.Version).NotTo(HaveKey("main/failing-pipeline"))
+			Expect(response.Metadata).NotTo(BeEmpty())
+		})
+	})
+
+	Context("when every pipeline is set", func() {
+		BeforeEach(func() {
+			request.Params.Pipelines = request.Params.Pipelines[:1]
+		})
+
+		It("writes the response to stdout", func() {
+			session := run()
+			Expect(session.ExitCode()).To(Equal(0))
+
+			var response concourse.OutResponse
+			err := json.Unmarshal(session.Out.Contents(), &response)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Version).To(HaveKey("main/passing-pipeline"))
+			Expect(session.Err.Contents()).NotTo(ContainSubstring("partial response"))
+		})
+	})
+})
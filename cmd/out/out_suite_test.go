@@ -0,0 +1,26 @@
+package main_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+
+	"testing"
+)
+
+var outPath string
+
+func TestOut(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Out Suite")
+}
+
+var _ = BeforeSuite(func() {
+	var err error
+	outPath, err = gexec.Build("github.com/concourse/concourse-pipeline-resource/cmd/out")
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	gexec.CleanupBuildArtifacts()
+})
@@ -0,0 +1,102 @@
+package concourse
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PipelineFilter selects pipelines by name. Each pattern is either a glob,
+// or a regular expression if it is delimited by slashes, e.g. `/^ci-.*$/`.
+type PipelineFilter struct {
+	Include []string `json:"include,omitempty" description:"Patterns matching the pipelines to include. Defaults to all pipelines."`
+	Exclude []string `json:"exclude,omitempty" description:"Patterns matching the pipelines to exclude."`
+}
+
+// Matches returns true if the name matches any include pattern, or there are
+// no include patterns, and does not match any exclude pattern.
+func (f PipelineFilter) Matches(name string) (bool, error) {
+	if len(f.Include) > 0 {
+		included, err := MatchAny(f.Include, name)
+		if err != nil || !included {
+			return false, err
+		}
+	}
+
+	excluded, err := MatchAny(f.Exclude, name)
+	if err != nil {
+		return false, err
+	}
+
+	return !excluded, nil
+}
+
+// Filter returns the pipelines, identified by their references, whose names
+// match the filter. Every instance of an instanced pipeline is matched by its
+// name.
+func (f PipelineFilter) Filter(pipelines []string) ([]string, error) {
+	filtered := []string{}
+	for _, pipelineName := range pipelines {
+		ref, err := ParsePipelineRef(pipelineName)
+		if err != nil {
+			return nil, err
+		}
+
+		matched, err := f.Matches(ref.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched {
+			filtered = append(filtered, pipelineName)
+		}
+	}
+
+	return filtered, nil
+}
+
+// MatchAny returns true if the name matches any of the patterns.
+func MatchAny(patterns []string, name string) (bool, error) {
+	return matchAny(patterns, name, MatchPattern)
+}
+
+// MatchAnyGlob returns true if the name matches any of the globs. Unlike
+// MatchAny, patterns delimited by slashes are globs too.
+func MatchAnyGlob(globs []string, name string) (bool, error) {
+	return matchAny(globs, name, MatchGlob)
+}
+
+func matchAny(patterns []string, name string, match func(string, string) (bool, error)) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := match(pattern, name)
+		if err != nil {
+			return false, err
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// MatchPattern matches the name against a glob, or against a regular
+// expression if the pattern is delimited by slashes.
+func MatchPattern(pattern string, name string) (bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false, err
+		}
+
+		return re.MatchString(name), nil
+	}
+
+	return MatchGlob(pattern, name)
+}
+
+// MatchGlob matches the name against a glob.
+func MatchGlob(glob string, name string) (bool, error) {
+	return path.Match(glob, name)
+}
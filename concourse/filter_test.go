@@ -0,0 +1,60 @@
+package concourse_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PipelineFilter", func() {
+	Describe("Filter", func() {
+		It("returns the pipelines which are included and not excluded", func() {
+			filter := concourse.PipelineFilter{
+				Include: []string{"ci-*", "/^release-[0-9]+$/"},
+				Exclude: []string{"ci-old"},
+			}
+
+			filtered, err := filter.Filter([]string{"ci-main", "ci-old", "release-1", "release-x", "other"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filtered).To(Equal([]string{"ci-main", "release-1"}))
+		})
+
+		It("matches every instance of an instanced pipeline by its name", func() {
+			filter := concourse.PipelineFilter{Include: []string{"ci"}}
+
+			filtered, err := filter.Filter([]string{"ci/branch:main", "ci/branch:dev", "other/branch:main"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filtered).To(Equal([]string{"ci/branch:main", "ci/branch:dev"}))
+		})
+
+		Context("when a pattern is malformed", func() {
+			It("returns an error", func() {
+				filter := concourse.PipelineFilter{Include: []string{"["}}
+
+				_, err := filter.Filter([]string{"ci"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
+
+var _ = Describe("MatchAnyGlob", func() {
+	It("matches the name against globs", func() {
+		Expect(concourse.MatchAnyGlob([]string{"ci-*", "other"}, "ci-main")).To(BeTrue())
+		Expect(concourse.MatchAnyGlob([]string{"ci-*", "other"}, "release")).To(BeFalse())
+	})
+
+	It("treats patterns delimited by slashes as globs", func() {
+		Expect(concourse.MatchAnyGlob([]string{"/ci/"}, "ci")).To(BeFalse())
+		Expect(concourse.MatchAnyGlob([]string{"/ci/"}, "/ci/")).To(BeTrue())
+	})
+
+	Context("when a glob is malformed", func() {
+		It("returns an error", func() {
+			_, err := concourse.MatchAnyGlob([]string{"["}, "ci")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package concourse
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PipelineMetadata describes what a put did to a pipeline. It is returned,
+// as JSON, as the value of the metadata entry named by the pipeline's
+// version key.
+type PipelineMetadata struct {
+	Team            string                 `json:"team"`
+	Name            string                 `json:"name"`
+	InstanceVars    map[string]interface{} `json:"instance_vars,omitempty"`
+	Status          string                 `json:"status"`
+	Error           string                 `json:"error,omitempty"`
+	PreviousVersion string                 `json:"previous_version,omitempty"`
+	Version         string                 `json:"version,omitempty"`
+	Changed         bool                   `json:"changed"`
+	URL             string                 `json:"url"`
+}
+
+// PipelineURL returns the URL of a pipeline in the web UI of target. The
+// instance vars of an instanced pipeline are given as the vars query
+// parameter, as the web UI expects.
+func PipelineURL(target string, teamName string, ref PipelineRef) string {
+	return strings.TrimSuffix(target, "/") +
+		"/teams/" + url.PathEscape(teamName) +
+		"/pipelines/" + url.PathEscape(ref.Name) +
+		ref.Query()
+}
@@ -0,0 +1,228 @@
+package concourse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	instanceVarsSeparator = "/"
+	instanceVarSeparator  = ","
+	instanceVarKeyValue   = ":"
+)
+
+// PipelineRef identifies a pipeline by its name and, for an instanced
+// pipeline, its instance vars.
+type PipelineRef struct {
+	Name         string
+	InstanceVars map[string]interface{}
+}
+
+// Ref returns the reference to the pipeline.
+func (p Pipeline) Ref() PipelineRef {
+	return PipelineRef{Name: p.Name, InstanceVars: p.InstanceVars}
+}
+
+// String formats the reference as fly does, e.g. `name/branch:main,pr:1`,
+// with nested instance vars flattened into dotted keys. It is the name alone
+// for a pipeline which is not instanced.
+func (r PipelineRef) String() string {
+	if len(r.InstanceVars) == 0 {
+		return r.Name
+	}
+
+	var pairs []string
+	for _, v := range FlattenInstanceVars(r.InstanceVars) {
+		pairs = append(pairs, v.Key+instanceVarKeyValue+v.Value)
+	}
+
+	return r.Name + instanceVarsSeparator + strings.Join(pairs, instanceVarSeparator)
+}
+
+// Vars returns vars with the instance vars added, as fly interpolates a
+// pipeline's config with its instance vars taking precedence over its vars.
+func (r PipelineRef) Vars(vars map[string]interface{}) map[string]interface{} {
+	if len(r.InstanceVars) == 0 {
+		return vars
+	}
+
+	merged := make(map[string]interface{}, len(vars)+len(r.InstanceVars))
+	for k, v := range vars {
+		merged[k] = v
+	}
+	for k, v := range r.InstanceVars {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// Query returns the query string, including the leading `?`, which
+// identifies an instanced pipeline to the API and web UI by its instance
+// vars. It is empty for a pipeline which is not instanced.
+func (r PipelineRef) Query() string {
+	if len(r.InstanceVars) == 0 {
+		return ""
+	}
+
+	vars, err := json.Marshal(JSONCompatible(r.InstanceVars))
+	if err != nil {
+		// Instance vars parsed from YAML or JSON always marshal to JSON
+		return ""
+	}
+
+	return "?" + url.Values{"vars": {string(vars)}}.Encode()
+}
+
+// InstanceVar is an instance var with a dotted key and a value formatted as
+// YAML.
+type InstanceVar struct {
+	Key   string
+	Value string
+}
+
+// FlattenInstanceVars returns the instance vars sorted by their dotted keys,
+// with their values formatted so that they can be parsed as YAML.
+func FlattenInstanceVars(instanceVars map[string]interface{}) []InstanceVar {
+	var flattened []InstanceVar
+	flattenInstanceVars(&flattened, "", instanceVars)
+
+	sort.Slice(flattened, func(i, j int) bool {
+		return flattened[i].Key < flattened[j].Key
+	})
+
+	return flattened
+}
+
+func flattenInstanceVars(flattened *[]InstanceVar, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, nested := range v {
+			flattenInstanceVars(flattened, joinKey(prefix, k), nested)
+		}
+	case map[interface{}]interface{}:
+		for k, nested := range v {
+			flattenInstanceVars(flattened, joinKey(prefix, fmt.Sprintf("%v", k)), nested)
+		}
+	default:
+		*flattened = append(*flattened, InstanceVar{Key: prefix, Value: formatInstanceVar(value)})
+	}
+}
+
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// formatInstanceVar leaves strings unquoted unless they contain separators
+// or would otherwise be parsed as something other than a string.
+func formatInstanceVar(value interface{}) string {
+	if s, ok := value.(string); ok && !needsQuoting(s) {
+		return s
+	}
+
+	b, err := json.Marshal(JSONCompatible(value))
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(b)
+}
+
+func needsQuoting(s string) bool {
+	if s == "" || strings.ContainsAny(s, `,:/"'{}[]#&*!|>%@`+"` \t\n") {
+		return true
+	}
+
+	var parsed interface{}
+	err := yaml.Unmarshal([]byte(s), &parsed)
+	if err != nil {
+		return true
+	}
+
+	_, isString := parsed.(string)
+	return !isString
+}
+
+// ParsePipelineRef parses a reference formatted by PipelineRef.String.
+func ParsePipelineRef(s string) (PipelineRef, error) {
+	parts := strings.SplitN(s, instanceVarsSeparator, 2)
+	if len(parts) == 1 {
+		return PipelineRef{Name: s}, nil
+	}
+
+	ref := PipelineRef{
+		Name:         parts[0],
+		InstanceVars: map[string]interface{}{},
+	}
+
+	for _, pair := range splitOutsideQuotes(parts[1], instanceVarSeparator) {
+		kv := strings.SplitN(pair, instanceVarKeyValue, 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return PipelineRef{}, fmt.Errorf("invalid instance var '%s' in pipeline '%s'", pair, s)
+		}
+
+		var value interface{}
+		err := yaml.Unmarshal([]byte(kv[1]), &value)
+		if err != nil {
+			return PipelineRef{}, fmt.Errorf("invalid value of instance var '%s' in pipeline '%s': %v", kv[0], s, err)
+		}
+
+		setDotted(ref.InstanceVars, kv[0], JSONCompatible(value))
+	}
+
+	return ref, nil
+}
+
+// splitOutsideQuotes splits s on sep where it is not within a quoted string
+// or a list or map.
+func splitOutsideQuotes(s string, sep string) []string {
+	var parts []string
+
+	depth := 0
+	quoted := false
+	escaped := false
+	start := 0
+
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func setDotted(m map[string]interface{}, key string, value interface{}) {
+	keys := strings.Split(key, ".")
+	for _, k := range keys[:len(keys)-1] {
+		nested, ok := m[k].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			m[k] = nested
+		}
+		m = nested
+	}
+
+	m[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,98 @@
+package concourse_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PipelineRef", func() {
+	Describe("String", func() {
+		It("is the name of a pipeline which is not instanced", func() {
+			ref := concourse.PipelineRef{Name: "some-pipeline"}
+			Expect(ref.String()).To(Equal("some-pipeline"))
+		})
+
+		It("appends the instance vars sorted by key", func() {
+			ref := concourse.PipelineRef{
+				Name: "some-pipeline",
+				InstanceVars: map[string]interface{}{
+					"pr":     1,
+					"branch": "main",
+					"config": map[string]interface{}{
+						"debug": true,
+					},
+				},
+			}
+
+			Expect(ref.String()).To(Equal("some-pipeline/branch:main,config.debug:true,pr:1"))
+		})
+
+		It("quotes strings which contain separators or are not strings as YAML", func() {
+			ref := concourse.PipelineRef{
+				Name: "some-pipeline",
+				InstanceVars: map[string]interface{}{
+					"branch":  "feature/a,b",
+					"version": "1",
+					"empty":   "",
+				},
+			}
+
+			Expect(ref.String()).To(Equal(`some-pipeline/branch:"feature/a,b",empty:"",version:"1"`))
+		})
+	})
+
+	Describe("ParsePipelineRef", func() {
+		It("parses a pipeline which is not instanced", func() {
+			ref, err := concourse.ParsePipelineRef("some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ref).To(Equal(concourse.PipelineRef{Name: "some-pipeline"}))
+		})
+
+		It("parses what String formats", func() {
+			original := concourse.PipelineRef{
+				Name: "some-pipeline",
+				InstanceVars: map[string]interface{}{
+					"branch":  "feature/a,b",
+					"version": "1",
+					"tags":    []interface{}{"a", "b"},
+					"config": map[string]interface{}{
+						"debug": true,
+					},
+				},
+			}
+
+			ref, err := concourse.ParsePipelineRef(original.String())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ref).To(Equal(original))
+		})
+
+		It("returns an error for an instance var without a value", func() {
+			_, err := concourse.ParsePipelineRef("some-pipeline/branch")
+			Expect(err).To(MatchError("invalid instance var 'branch' in pipeline 'some-pipeline/branch'"))
+		})
+	})
+})
+
+var _ = Describe("DependencyKeys", func() {
+	It("qualifies dependencies by team, unless they already are", func() {
+		p := concourse.Pipeline{
+			TeamName: "main",
+			DependsOn: []string{
+				"a",
+				"other/b",
+				"c/branch:main",
+				"other/d/branch:feature/x",
+			},
+		}
+
+		Expect(p.DependencyKeys()).To(Equal([]string{
+			"main/a",
+			"other/b",
+			"main/c/branch:main",
+			"other/d/branch:feature/x",
+		}))
+	})
+})
@@ -9,6 +9,18 @@ func SanitizedSource(source Source) map[string]string {
 		if t.Password != "" {
 			s[t.Password] = fmt.Sprintf("***REDACTED-PASSWORD-TEAM-%d***", i)
 		}
+
+		if t.Token != "" {
+			s[t.Token] = fmt.Sprintf("***REDACTED-TOKEN-TEAM-%d***", i)
+		}
+
+		if t.ClientSecret != "" {
+			s[t.ClientSecret] = fmt.Sprintf("***REDACTED-CLIENT-SECRET-TEAM-%d***", i)
+		}
+	}
+
+	if source.Archive != nil && source.Archive.Token != "" {
+		s[source.Archive.Token] = "***REDACTED-ARCHIVE-TOKEN***"
 	}
 
 	return s
@@ -1,15 +1,46 @@
 package concourse
 
+const (
+	ClientFly = "fly"
+	ClientAPI = "api"
+)
+
+// Source, and the types it contains, are also described by the JSON Schema
+// in the schema package, which is generated from the description, enum,
+// minimum and required tags below.
 type Source struct {
-	Target   string `json:"target"`
-	Teams    []Team `json:"teams"`
-	Insecure string `json:"insecure"`
+	Target   string `json:"target" description:"URL of the Concourse instance. Defaults to the URL of the Concourse running the resource."`
+	Teams    []Team `json:"teams" required:"true" description:"Teams whose pipelines are checked, fetched and set."`
+	Insecure string `json:"insecure" description:"Skip TLS verification when connecting to Concourse."`
+	Client   string `json:"client,omitempty" enum:"fly,api" description:"How the resource talks to Concourse. Defaults to fly."`
+
+	MaxConcurrency int      `json:"max_concurrency,omitempty" minimum:"0" description:"Maximum number of teams and pipelines handled in parallel. Defaults to 1."`
+	SensitiveVars  []string `json:"sensitive_vars,omitempty" description:"Patterns matching the names of vars whose values are redacted from logs."`
+	LogLevel       string   `json:"log_level,omitempty" enum:"debug,info,warn,error" description:"Minimum level of messages written to the log file. Defaults to debug."`
+	LogFormat      string   `json:"log_format,omitempty" enum:"text,json" description:"Format of the log file. Defaults to text."`
+
+	AllowUnknownFields bool `json:"allow_unknown_fields,omitempty" description:"Ignore unrecognised keys instead of failing."`
+	ValidateSchema     bool `json:"validate_schema,omitempty" description:"Validate the request against the published JSON Schema before running."`
+
+	Archive *Archive `json:"archive,omitempty" description:"Where the config of every version of each pipeline is kept, so that versions which are no longer current can be fetched."`
+}
+
+// Archive is a store of the config of every version of each pipeline. check
+// and out store the configs they see, and in fetches the configs of the
+// requested version from it once they are no longer current.
+type Archive struct {
+	URL   string `json:"url" required:"true" description:"A file URL of a directory, or an HTTP(S) URL which configs are fetched from with GET and stored at with PUT."`
+	Token string `json:"token,omitempty" description:"Bearer token sent to an HTTP(S) archive."`
 }
 
 type Team struct {
-	Name     string `json:"name"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Name         string         `json:"name" required:"true" description:"Name of the team."`
+	Username     string         `json:"username" description:"Basic auth username for the team."`
+	Password     string         `json:"password" description:"Basic auth password for the team."`
+	Token        string         `json:"token,omitempty" description:"Bearer token for the team, instead of a username and password."`
+	ClientID     string         `json:"client_id,omitempty" description:"OAuth client ID used to request a token with the client credentials grant."`
+	ClientSecret string         `json:"client_secret,omitempty" description:"OAuth client secret. Required if client_id is provided."`
+	Pipelines    PipelineFilter `json:"pipelines" description:"Restricts which of the team's pipelines are checked and fetched."`
 }
 
 type CheckRequest struct {
@@ -28,8 +59,27 @@ type InRequest struct {
 }
 
 type InParams struct {
+	Teams        []string `json:"teams,omitempty" description:"Teams whose pipelines are fetched. Each must be one of the teams in source. Defaults to every team."`
+	Pipelines    []string `json:"pipelines,omitempty" description:"Patterns matching the names of the pipelines to fetch. Defaults to every pipeline."`
+	SkipDownload bool     `json:"skip_download,omitempty" description:"Fetch no pipelines, e.g. for a get which only triggers a job."`
+	ExportStatus bool     `json:"export_status,omitempty" description:"Also write the runtime status of each pipeline, including its jobs' latest builds, to a JSON file alongside its config."`
+
+	Layout   string `json:"layout,omitempty" enum:"flat,team-dirs" description:"How configs are laid out: flat as <team>-<pipeline>.yml, or team-dirs as <team>/<pipeline>.yml. Defaults to flat."`
+	Format   string `json:"format,omitempty" enum:"yaml,normalized-yaml,json" description:"Format configs are written in: yaml as returned by Concourse, normalized-yaml with sorted keys, or json. Defaults to yaml."`
+	Filename string `json:"filename,omitempty" description:"Template of the path each config is written to, relative to the destination, instead of the layout, e.g. {{.Team}}/{{.Name}}.{{.Ext}}."`
 }
 
+const (
+	InLayoutFlat     = "flat"
+	InLayoutTeamDirs = "team-dirs"
+)
+
+const (
+	InFormatYAML           = "yaml"
+	InFormatNormalizedYAML = "normalized-yaml"
+	InFormatJSON           = "json"
+)
+
 type InResponse struct {
 	Version  Version    `json:"version"`
 	Metadata []Metadata `json:"metadata"`
@@ -46,18 +96,69 @@ type OutRequest struct {
 }
 
 type OutParams struct {
-	Pipelines     []Pipeline `json:"pipelines,omitempty"`
-	PipelinesFile string     `json:"pipelines_file,omitempty"`
+	Pipelines     []Pipeline `json:"pipelines,omitempty" description:"Pipelines to set. Exactly one of pipelines and pipelines_file must be provided."`
+	PipelinesFile string     `json:"pipelines_file,omitempty" description:"Path to a YAML file containing the pipelines to set."`
+	DryRun        bool       `json:"dry_run,omitempty" description:"Report the changes which would be made without making them."`
+	Prune         []Prune    `json:"prune,omitempty" description:"Teams whose pipelines which are not declared are removed."`
+	Concurrency   int        `json:"concurrency,omitempty" minimum:"0" description:"Maximum number of pipelines to set at once. Defaults to 1."`
+	FailFast      *bool      `json:"fail_fast,omitempty" description:"Stop setting pipelines after the first failure. If false, every pipeline is attempted. Defaults to true."`
+}
+
+const (
+	PruneActionDestroy = "destroy"
+	PruneActionPause   = "pause"
+	PruneActionArchive = "archive"
+)
+
+type Prune struct {
+	TeamName string   `json:"team" required:"true" description:"Team whose undeclared pipelines are pruned."`
+	Action   string   `json:"action,omitempty" enum:"destroy,pause,archive" description:"What to do with undeclared pipelines. Defaults to destroy."`
+	Keep     []string `json:"keep,omitempty" description:"Globs matching the names of undeclared pipelines which are kept."`
 }
 
 type Pipeline struct {
-	Name       string                 `json:"name" yaml:"name"`
-	ConfigFile string                 `json:"config_file" yaml:"config_file"`
-	VarsFiles  []string               `json:"vars_files" yaml:"vars_files"`
-	Vars       map[string]interface{} `json:"vars" yaml:"vars"`
-	TeamName   string                 `json:"team" yaml:"team"`
-	Unpaused   bool                   `json:"unpaused" yaml:"unpaused"`
-	Exposed    bool                   `json:"exposed" yaml:"exposed"`
+	Name       string                 `json:"name" yaml:"name" description:"Name of the pipeline. With config_glob, a template which defaults to {{.Base}}."`
+	ConfigFile string                 `json:"config_file" yaml:"config_file" description:"Path to the pipeline config. Exactly one of config_file and config_glob must be provided."`
+	VarsFiles  []string               `json:"vars_files" yaml:"vars_files" description:"Paths to files of vars to interpolate into the config."`
+	Vars       map[string]interface{} `json:"vars" yaml:"vars" description:"Vars to interpolate into the config."`
+
+	InstanceVars map[string]interface{} `json:"instance_vars,omitempty" yaml:"instance_vars,omitempty" description:"Instance vars of an instanced pipeline, which are also interpolated into the config."`
+	TeamName     string                 `json:"team" yaml:"team" required:"true" description:"Team the pipeline belongs to. With config_glob, a template."`
+	Unpaused     bool                   `json:"unpaused" yaml:"unpaused" description:"Unpause the pipeline after setting it."`
+	Exposed      bool                   `json:"exposed" yaml:"exposed" description:"Expose the pipeline after setting it."`
+
+	// Paused and Public are enforced in both directions, unlike Unpaused and
+	// Exposed. If not provided the pipeline is left as it is.
+	Paused     *bool  `json:"paused,omitempty" yaml:"paused,omitempty" description:"Pause the pipeline if true, or unpause it if false. Left as it is if not provided."`
+	Public     *bool  `json:"public,omitempty" yaml:"public,omitempty" description:"Expose the pipeline if true, or hide it if false. Left as it is if not provided."`
+	Archived   bool   `json:"archived,omitempty" yaml:"archived,omitempty" description:"Archive the pipeline instead of setting it. Archived pipelines which are not archived: true are unarchived by setting them."`
+	RenameFrom string `json:"rename_from,omitempty" yaml:"rename_from,omitempty" description:"Existing pipeline to rename to name, preserving its build history, before setting it."`
+
+	SensitiveVars []string `json:"sensitive_vars,omitempty" yaml:"sensitive_vars,omitempty" description:"Patterns matching the names of vars whose values are redacted from logs."`
+
+	// ConfigGlob expands the entry into a pipeline per matching config,
+	// with Name and TeamName as templates over the config's path.
+	ConfigGlob   string   `json:"config_glob,omitempty" yaml:"config_glob,omitempty" description:"Glob, or directory, matching pipeline configs. Each config becomes a pipeline."`
+	DirVarsFiles []string `json:"dir_vars_files,omitempty" yaml:"dir_vars_files,omitempty" description:"Names of vars files which, when present in the directory of a config matched by config_glob or one of its parents, are used for that pipeline."`
+
+	Render *Render `json:"render,omitempty" yaml:"render,omitempty" description:"Renders the config with a templating engine before it is set."`
+
+	// DependsOn names pipelines declared earlier which must be set before
+	// this one, as `name` for a pipeline of the same team or `team/name`.
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty" description:"Pipelines declared earlier which must be set before this one, as name for a pipeline of the same team or team/name, with the instance vars of an instanced pipeline following its name, e.g. name/branch:main."`
+}
+
+const (
+	RenderEngineTemplate = "template"
+	RenderEngineYtt      = "ytt"
+	RenderEngineJsonnet  = "jsonnet"
+)
+
+type Render struct {
+	Engine string                 `json:"engine" yaml:"engine" required:"true" enum:"template,ytt,jsonnet" description:"Templating engine which renders the config."`
+	Inputs []string               `json:"inputs,omitempty" yaml:"inputs,omitempty" description:"Additional files for the engine: templates for template, files for ytt, library directories for jsonnet."`
+	Data   map[string]interface{} `json:"data,omitempty" yaml:"data,omitempty" description:"Data for the engine: the template's data, ytt data values, or jsonnet external code."`
+	Binary string                 `json:"binary,omitempty" yaml:"binary,omitempty" description:"Path to the ytt or jsonnet binary, relative to the sources directory if it contains a slash. Defaults to the engine's name, found on the PATH."`
 }
 
 type OutResponse struct {
@@ -0,0 +1,63 @@
+package concourse
+
+import (
+	"fmt"
+	"sort"
+)
+
+// JSONCompatible converts a value decoded from YAML into the types
+// encoding/json decodes to: maps with interface{} keys become maps with
+// string keys and integers become float64. The result can be marshalled to
+// JSON and compared with reflect.DeepEqual regardless of how it was
+// formatted.
+func JSONCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			m[fmt.Sprintf("%v", k)] = JSONCompatible(nested)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			m[k] = JSONCompatible(nested)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, nested := range v {
+			l[i] = JSONCompatible(nested)
+		}
+		return l
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	default:
+		return value
+	}
+}
+
+// SortedKeys returns the keys of m in order.
+func SortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StringContains returns true if str is an element of slice.
+func StringContains(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
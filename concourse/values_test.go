@@ -0,0 +1,42 @@
+package concourse_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("Values", func() {
+	Describe("JSONCompatible", func() {
+		It("converts a value decoded from YAML into the types decoded from JSON", func() {
+			var value interface{}
+			err := yaml.Unmarshal([]byte("a: {b: [1, 2.5, {1: c}]}"), &value)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(concourse.JSONCompatible(value)).To(Equal(map[string]interface{}{
+				"a": map[string]interface{}{
+					"b": []interface{}{
+						float64(1),
+						2.5,
+						map[string]interface{}{"1": "c"},
+					},
+				},
+			}))
+		})
+	})
+
+	Describe("SortedKeys", func() {
+		It("returns the keys in order", func() {
+			keys := concourse.SortedKeys(map[string]interface{}{"b": 1, "c": 2, "a": 3})
+			Expect(keys).To(Equal([]string{"a", "b", "c"}))
+		})
+	})
+
+	Describe("StringContains", func() {
+		It("returns whether the string is an element of the slice", func() {
+			Expect(concourse.StringContains([]string{"a", "b"}, "b")).To(BeTrue())
+			Expect(concourse.StringContains([]string{"a", "b"}, "c")).To(BeFalse())
+		})
+	})
+})
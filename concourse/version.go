@@ -0,0 +1,104 @@
+package concourse
+
+import (
+	"reflect"
+	"strings"
+)
+
+const versionKeySeparator = "/"
+
+// VersionKey returns the key under which the version of a pipeline is
+// recorded. Keys are qualified by team so that pipelines with the same name
+// in different teams do not collide.
+func VersionKey(teamName string, pipelineName string) string {
+	return teamName + versionKeySeparator + pipelineName
+}
+
+// ParseVersionKey splits a version key into its team and pipeline names.
+// Keys recorded by earlier versions of this resource are not team-qualified;
+// for those the returned team name is empty.
+func ParseVersionKey(key string) (teamName string, pipelineName string) {
+	parts := strings.SplitN(key, versionKeySeparator, 2)
+	if len(parts) < 2 {
+		return "", key
+	}
+
+	return parts[0], parts[1]
+}
+
+// IsLegacy returns true if any key of the version is not team-qualified.
+func (v Version) IsLegacy() bool {
+	for key := range v {
+		if teamName, _ := ParseVersionKey(key); teamName == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LegacyVersion converts a team-qualified version into the legacy format
+// keyed only by pipeline name. It returns false if the conversion would lose
+// information because pipelines with the same name exist in multiple teams.
+func LegacyVersion(v Version) (Version, bool) {
+	legacy := Version{}
+
+	for key, value := range v {
+		_, pipelineName := ParseVersionKey(key)
+		if _, found := legacy[pipelineName]; found {
+			return nil, false
+		}
+		legacy[pipelineName] = value
+	}
+
+	return legacy, true
+}
+
+// MigrateVersion returns the version which check should emit, given the
+// previously stored version and the current, team-qualified, one.
+//
+// If the stored version uses the legacy format but describes exactly the same
+// pipelines as the current version, the stored version is returned so that
+// upgrading the resource does not trigger a new version by itself. As soon as
+// any pipeline changes, the team-qualified version is returned instead.
+func MigrateVersion(previous Version, current Version) Version {
+	if len(previous) == 0 || !previous.IsLegacy() {
+		return current
+	}
+
+	legacy, ok := LegacyVersion(current)
+	if !ok || !reflect.DeepEqual(legacy, previous) {
+		return current
+	}
+
+	return previous
+}
+
+// DependencyKeys returns the version keys of the pipelines p depends on.
+// Dependencies which are not team-qualified belong to the team of p. A
+// dependency on an instanced pipeline of the same team, `name/key:value`, is
+// told apart from `team/name` by the instance vars which follow the slash.
+func (p Pipeline) DependencyKeys() []string {
+	var keys []string
+	for _, d := range p.DependsOn {
+		if isTeamQualified(d) {
+			keys = append(keys, d)
+		} else {
+			keys = append(keys, VersionKey(p.TeamName, d))
+		}
+	}
+
+	return keys
+}
+
+func isTeamQualified(dependency string) bool {
+	parts := strings.SplitN(dependency, versionKeySeparator, 2)
+	if len(parts) < 2 {
+		return false
+	}
+
+	// For `name/key:value` the rest is instance vars, whose first key
+	// contains no slash.
+	keyEnd := strings.Index(parts[1], instanceVarKeyValue)
+	return keyEnd == -1 || strings.Contains(parts[1][:keyEnd], versionKeySeparator)
+}
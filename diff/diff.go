@@ -0,0 +1,206 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"gopkg.in/yaml.v2"
+)
+
+// namedSections are the top-level sections of a pipeline config whose
+// entries are identified by their name.
+var namedSections = []string{"jobs", "resources", "resource_types", "groups"}
+
+// Changes lists the names of entries which differ between two configs.
+type Changes struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Empty returns true if there are no changes.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// Diff describes the differences between two pipeline configs.
+type Diff struct {
+	Jobs          Changes `json:"jobs"`
+	Resources     Changes `json:"resources"`
+	ResourceTypes Changes `json:"resource_types"`
+	Groups        Changes `json:"groups"`
+
+	// Other lists any other top-level keys of the config.
+	Other Changes `json:"other"`
+}
+
+// HasChanges returns true if the configs differ.
+func (d Diff) HasChanges() bool {
+	for _, c := range d.sections() {
+		if !c.changes.Empty() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String renders the diff in a human-readable form.
+func (d Diff) String() string {
+	if !d.HasChanges() {
+		return "no changes\n"
+	}
+
+	var b strings.Builder
+	for _, s := range d.sections() {
+		for _, name := range s.changes.Added {
+			fmt.Fprintf(&b, "+ %s: %s\n", s.name, name)
+		}
+		for _, name := range s.changes.Removed {
+			fmt.Fprintf(&b, "- %s: %s\n", s.name, name)
+		}
+		for _, name := range s.changes.Changed {
+			fmt.Fprintf(&b, "~ %s: %s\n", s.name, name)
+		}
+	}
+
+	return b.String()
+}
+
+type section struct {
+	name    string
+	changes Changes
+}
+
+func (d Diff) sections() []section {
+	return []section{
+		{"jobs", d.Jobs},
+		{"resources", d.Resources},
+		{"resource_types", d.ResourceTypes},
+		{"groups", d.Groups},
+		{"other", d.Other},
+	}
+}
+
+// Configs compares two YAML pipeline configs. An empty config is treated as
+// a pipeline which does not exist.
+func Configs(current []byte, proposed []byte) (Diff, error) {
+	currentConfig, err := parse(current)
+	if err != nil {
+		return Diff{}, fmt.Errorf("failed to parse current config: %v", err)
+	}
+
+	proposedConfig, err := parse(proposed)
+	if err != nil {
+		return Diff{}, fmt.Errorf("failed to parse proposed config: %v", err)
+	}
+
+	d := Diff{
+		Jobs:          compareSection(currentConfig, proposedConfig, "jobs"),
+		Resources:     compareSection(currentConfig, proposedConfig, "resources"),
+		ResourceTypes: compareSection(currentConfig, proposedConfig, "resource_types"),
+		Groups:        compareSection(currentConfig, proposedConfig, "groups"),
+	}
+
+	for _, sectionName := range namedSections {
+		delete(currentConfig, sectionName)
+		delete(proposedConfig, sectionName)
+	}
+
+	d.Other = compare(currentConfig, proposedConfig)
+
+	return d, nil
+}
+
+// Equal returns true if two YAML pipeline configs are semantically
+// identical, regardless of how they are formatted. Unlike Configs, which
+// matches entries by name, the order of entries is significant, as
+// Concourse shows them, such as groups, in order.
+func Equal(current []byte, proposed []byte) (bool, error) {
+	currentConfig, err := parse(current)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse current config: %v", err)
+	}
+
+	proposedConfig, err := parse(proposed)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse proposed config: %v", err)
+	}
+
+	return reflect.DeepEqual(currentConfig, proposedConfig), nil
+}
+
+func parse(config []byte) (map[string]interface{}, error) {
+	var raw interface{}
+	err := yaml.Unmarshal(config, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	normalized, ok := concourse.JSONCompatible(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config must be a map")
+	}
+
+	return normalized, nil
+}
+
+func compareSection(current map[string]interface{}, proposed map[string]interface{}, sectionName string) Changes {
+	return compare(byName(current[sectionName]), byName(proposed[sectionName]))
+}
+
+func byName(section interface{}) map[string]interface{} {
+	entries := make(map[string]interface{})
+
+	list, ok := section.([]interface{})
+	if !ok {
+		return entries
+	}
+
+	for i, entry := range list {
+		name := fmt.Sprintf("#%d", i)
+		if m, ok := entry.(map[string]interface{}); ok {
+			if n, ok := m["name"].(string); ok {
+				name = n
+			}
+		}
+		entries[name] = entry
+	}
+
+	return entries
+}
+
+func compare(current map[string]interface{}, proposed map[string]interface{}) Changes {
+	changes := Changes{}
+
+	for name, p := range proposed {
+		c, found := current[name]
+		if !found {
+			changes.Added = append(changes.Added, name)
+			continue
+		}
+
+		if !reflect.DeepEqual(c, p) {
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+
+	for name := range current {
+		if _, found := proposed[name]; !found {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+
+	return changes
+}
@@ -0,0 +1,182 @@
+package diff_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/diff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Configs", func() {
+	var (
+		current  []byte
+		proposed []byte
+	)
+
+	BeforeEach(func() {
+		current = []byte(`---
+resources:
+- name: repo
+  type: git
+  source: {uri: "https://example.com/repo.git"}
+- name: old-repo
+  type: git
+jobs:
+- name: build
+  plan:
+  - get: repo
+- name: test
+  plan:
+  - get: repo
+groups:
+- name: all
+  jobs: [build, test]
+`)
+
+		proposed = []byte(`---
+groups:
+- jobs: [build, test]
+  name: all
+jobs:
+- name: build
+  plan:
+  - get: repo
+    trigger: true
+- plan:
+  - get: repo
+  name: test
+- name: deploy
+  plan:
+  - get: repo
+resources:
+- name: repo
+  source:
+    uri: https://example.com/repo.git
+  type: git
+display:
+  background_image: some-image.png
+`)
+	})
+
+	It("reports added, removed and changed entries by name", func() {
+		d, err := diff.Configs(current, proposed)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(d.HasChanges()).To(BeTrue())
+		Expect(d.Jobs).To(Equal(diff.Changes{
+			Added:   []string{"deploy"},
+			Changed: []string{"build"},
+		}))
+		Expect(d.Resources).To(Equal(diff.Changes{
+			Removed: []string{"old-repo"},
+		}))
+		Expect(d.ResourceTypes.Empty()).To(BeTrue())
+		Expect(d.Groups.Empty()).To(BeTrue())
+		Expect(d.Other).To(Equal(diff.Changes{
+			Added: []string{"display"},
+		}))
+	})
+
+	It("renders the diff in a human-readable form", func() {
+		d, err := diff.Configs(current, proposed)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(d.String()).To(Equal(`+ jobs: deploy
+~ jobs: build
+- resources: old-repo
++ other: display
+`))
+	})
+
+	Context("when the configs are semantically identical", func() {
+		BeforeEach(func() {
+			proposed = current
+		})
+
+		It("reports no changes", func() {
+			d, err := diff.Configs(current, proposed)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.HasChanges()).To(BeFalse())
+			Expect(d.String()).To(Equal("no changes\n"))
+		})
+	})
+
+	Context("when there is no current config", func() {
+		It("reports every entry as added", func() {
+			d, err := diff.Configs(nil, proposed)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.Jobs.Added).To(Equal([]string{"build", "deploy", "test"}))
+			Expect(d.Resources.Added).To(Equal([]string{"repo"}))
+		})
+	})
+
+	Context("when a config is not valid YAML", func() {
+		It("returns an error", func() {
+			_, err := diff.Configs(current, []byte("jobs: ["))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Equal", func() {
+	var current []byte
+
+	BeforeEach(func() {
+		current = []byte(`---
+jobs:
+- name: build
+- name: test
+groups:
+- name: build
+  jobs: [build]
+- name: test
+  jobs: [test]
+`)
+	})
+
+	It("is true for configs which are formatted differently", func() {
+		equal, err := diff.Equal(current, []byte(`{"groups": [{"jobs": ["build"], "name": "build"}, {"jobs": ["test"], "name": "test"}], "jobs": [{"name": "build"}, {"name": "test"}]}`))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(equal).To(BeTrue())
+	})
+
+	Context("when only the order of the groups changes", func() {
+		var proposed []byte
+
+		BeforeEach(func() {
+			proposed = []byte(`---
+jobs:
+- name: build
+- name: test
+groups:
+- name: test
+  jobs: [test]
+- name: build
+  jobs: [build]
+`)
+		})
+
+		It("is false", func() {
+			equal, err := diff.Equal(current, proposed)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(equal).To(BeFalse())
+		})
+
+		It("is not reported by name", func() {
+			d, err := diff.Configs(current, proposed)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.HasChanges()).To(BeFalse())
+		})
+	})
+
+	Context("when a config is not valid YAML", func() {
+		It("returns an error", func() {
+			_, err := diff.Equal(current, []byte("jobs: ["))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
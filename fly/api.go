@@ -0,0 +1,441 @@
+package fly
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	"github.com/concourse/concourse-pipeline-resource/render"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	apiPrefix = "/api/v1"
+
+	configVersionHeader = "X-Concourse-Config-Version"
+)
+
+type apiCommand struct {
+	logger  logger.Logger
+	secrets *redact.Secrets
+
+	mu       sync.RWMutex
+	sessions map[string]session
+}
+
+// session is the login to a team. Each team has its own URL and client, so
+// that logins to teams with different TLS settings do not affect each other.
+type session struct {
+	url        string
+	httpClient *http.Client
+	tokenType  string
+	token      string
+}
+
+// NewAPICommand returns a Command which talks to the Concourse API directly
+// instead of invoking the fly binary. Tokens obtained when logging in are
+// added to secrets.
+func NewAPICommand(logger logger.Logger, secrets *redact.Secrets) Command {
+	return &apiCommand{
+		logger:   logger,
+		secrets:  secrets,
+		sessions: make(map[string]session),
+	}
+}
+
+func (a *apiCommand) Login(
+	url string,
+	teamName string,
+	credentials Credentials,
+	insecure bool,
+) ([]byte, error) {
+	if url == "" {
+		return nil, fmt.Errorf("url cannot be empty in apiCommand.Login")
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+			Proxy:           http.ProxyFromEnvironment,
+		},
+	}
+
+	var tokenType, token string
+	switch {
+	case credentials.Token != "":
+		tokenType, token = "Bearer", credentials.Token
+	case credentials.ClientID != "" && credentials.ClientSecret != "",
+		credentials.Username != "" && credentials.Password != "":
+		var err error
+		tokenType, token, err = requestToken(a.logger, a.secrets, httpClient, url, teamName, credentials)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	a.mu.Lock()
+	a.sessions[teamName] = session{
+		url:        strings.TrimRight(url, "/"),
+		httpClient: httpClient,
+		tokenType:  tokenType,
+		token:      token,
+	}
+	a.mu.Unlock()
+
+	return []byte(fmt.Sprintf("logged in to team '%s'\n", teamName)), nil
+}
+
+func (a *apiCommand) Pipelines(teamName string) ([]string, error) {
+	body, _, err := a.do(teamName, "GET", teamPath(teamName, "pipelines"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return pipelineRefs(body)
+}
+
+func (a *apiCommand) PipelineStates(teamName string) ([]PipelineState, error) {
+	body, _, err := a.do(teamName, "GET", teamPath(teamName, "pipelines"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var ps []PipelineState
+
+	err = json.Unmarshal(body, &ps)
+	if err != nil {
+		return nil, err
+	}
+
+	return ps, nil
+}
+
+func (a *apiCommand) Jobs(teamName string, pipelineName string) ([]Job, error) {
+	path, err := pipelinePath(teamName, pipelineName, "jobs")
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := a.do(teamName, "GET", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []Job
+
+	err = json.Unmarshal(body, &jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (a *apiCommand) GetPipeline(teamName string, pipelineName string) ([]byte, error) {
+	path, err := pipelinePath(teamName, pipelineName, "config")
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := a.do(teamName, "GET", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var c struct {
+		Config json.RawMessage `json:"config"`
+	}
+
+	err = json.Unmarshal(body, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	// The config is converted to YAML as fly get-pipeline does, so that a
+	// pipeline has the same version, and its config the same contents,
+	// whichever client fetched it.
+	var config interface{}
+	err = yaml.Unmarshal(c.Config, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(config)
+}
+
+func (a *apiCommand) SetPipeline(
+	teamName string,
+	pipelineName string,
+	configFilepath string,
+	varsFilepaths []string,
+	vars map[string]interface{},
+) ([]byte, error) {
+	ref, err := concourse.ParsePipelineRef(pipelineName)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := render.Config(configFilepath, varsFilepaths, ref.Vars(vars))
+	if err != nil {
+		return nil, err
+	}
+
+	configPath, err := pipelinePath(teamName, pipelineName, "config")
+	if err != nil {
+		return nil, err
+	}
+
+	// The current config version guards against concurrent updates; a new
+	// pipeline has no version.
+	_, header, err := a.do(teamName, "GET", configPath, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	requestHeader := http.Header{
+		"Content-Type": {"application/x-yaml"},
+	}
+	if header != nil {
+		requestHeader.Set(configVersionHeader, header.Get(configVersionHeader))
+	}
+
+	body, _, err := a.do(teamName, "PUT", configPath, requestHeader, bytes.NewReader(config))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Warnings []struct {
+			Message string `json:"message"`
+		} `json:"warnings"`
+	}
+
+	output := fmt.Sprintf("configuration updated for pipeline '%s'\n", pipelineName)
+
+	if len(body) > 0 && json.Unmarshal(body, &resp) == nil {
+		for _, w := range resp.Warnings {
+			output += fmt.Sprintf("WARNING: %s\n", w.Message)
+		}
+	}
+
+	return []byte(output), nil
+}
+
+func (a *apiCommand) DestroyPipeline(teamName string, pipelineName string) ([]byte, error) {
+	path, err := pipelinePath(teamName, pipelineName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = a.do(teamName, "DELETE", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("pipeline '%s' destroyed\n", pipelineName)), nil
+}
+
+func (a *apiCommand) UnpausePipeline(teamName string, pipelineName string) ([]byte, error) {
+	path, err := pipelinePath(teamName, pipelineName, "unpause")
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = a.do(teamName, "PUT", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("unpaused '%s'\n", pipelineName)), nil
+}
+
+func (a *apiCommand) PausePipeline(teamName string, pipelineName string) ([]byte, error) {
+	path, err := pipelinePath(teamName, pipelineName, "pause")
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = a.do(teamName, "PUT", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("paused '%s'\n", pipelineName)), nil
+}
+
+func (a *apiCommand) ArchivePipeline(teamName string, pipelineName string) ([]byte, error) {
+	path, err := pipelinePath(teamName, pipelineName, "archive")
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = a.do(teamName, "PUT", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("archived '%s'\n", pipelineName)), nil
+}
+
+func (a *apiCommand) ExposePipeline(teamName string, pipelineName string) ([]byte, error) {
+	path, err := pipelinePath(teamName, pipelineName, "expose")
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = a.do(teamName, "PUT", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("exposed '%s'\n", pipelineName)), nil
+}
+
+func (a *apiCommand) HidePipeline(teamName string, pipelineName string) ([]byte, error) {
+	path, err := pipelinePath(teamName, pipelineName, "hide")
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = a.do(teamName, "PUT", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("hid '%s'\n", pipelineName)), nil
+}
+
+func (a *apiCommand) RenamePipeline(teamName string, oldName string, newName string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"name": newName})
+	if err != nil {
+		// Untested as a map of strings always marshals to JSON
+		return nil, err
+	}
+
+	header := http.Header{
+		"Content-Type": {"application/json"},
+	}
+
+	path, err := pipelinePath(teamName, oldName, "rename")
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, err = a.do(teamName, "PUT", path, header, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("pipeline successfully renamed from '%s' to '%s'\n", oldName, newName)), nil
+}
+
+// Cleanup forgets the session, and so the token, of every team.
+func (a *apiCommand) Cleanup() {
+	a.mu.Lock()
+	a.sessions = make(map[string]session)
+	a.mu.Unlock()
+}
+
+func teamPath(teamName string, suffix string) string {
+	return fmt.Sprintf("%s/teams/%s/%s", apiPrefix, url.PathEscape(teamName), suffix)
+}
+
+// pipelinePath returns the path of the pipeline referred to by pipelineRef,
+// which identifies an instanced pipeline by its instance vars.
+func pipelinePath(teamName string, pipelineRef string, suffix string) (string, error) {
+	ref, err := concourse.ParsePipelineRef(pipelineRef)
+	if err != nil {
+		return "", err
+	}
+
+	path := teamPath(teamName, "pipelines/"+url.PathEscape(ref.Name))
+	if suffix != "" {
+		path += "/" + suffix
+	}
+
+	return path + ref.Query(), nil
+}
+
+type statusError struct {
+	method     string
+	path       string
+	statusCode int
+	body       []byte
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("%s %s returned %d - %s", e.method, e.path, e.statusCode, e.body)
+}
+
+func isNotFound(err error) bool {
+	se, ok := err.(statusError)
+	return ok && se.statusCode == http.StatusNotFound
+}
+
+func (a *apiCommand) do(
+	teamName string,
+	method string,
+	path string,
+	header http.Header,
+	body io.Reader,
+) ([]byte, http.Header, error) {
+	a.mu.RLock()
+	sess, loggedIn := a.sessions[teamName]
+	a.mu.RUnlock()
+
+	if !loggedIn {
+		return nil, nil, fmt.Errorf("login to team '%s' must be performed before %s %s", teamName, method, path)
+	}
+
+	tokenType := sess.tokenType
+	token := sess.token
+
+	req, err := http.NewRequest(method, sess.url+path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if token != "" {
+		if tokenType == "" {
+			tokenType = "Bearer"
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("%s %s", tokenType, token))
+	}
+
+	a.logger.Debugf("Starting API request: %s %s\n", method, path)
+	resp, err := sess.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, statusError{
+			method:     method,
+			path:       path,
+			statusCode: resp.StatusCode,
+			body:       respBody,
+		}
+	}
+
+	return respBody, resp.Header, nil
+}
@@ -0,0 +1,678 @@
+package fly_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/concourse/concourse-pipeline-resource/fly"
+	"github.com/concourse/concourse-pipeline-resource/internal/testserver"
+	"github.com/concourse/concourse-pipeline-resource/logger/loggerfakes"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("APICommand", func() {
+	var (
+		server *testserver.Server
+
+		apiCommand fly.Command
+		teamName   string
+
+		fakeLogger *loggerfakes.FakeLogger
+		secrets    *redact.Secrets
+	)
+
+	BeforeEach(func() {
+		server = testserver.NewServer()
+		teamName = "main"
+
+		fakeLogger = &loggerfakes.FakeLogger{}
+		secrets = redact.NewSecrets()
+
+		apiCommand = fly.NewAPICommand(fakeLogger, secrets)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	login := func() {
+		server.AppendHandlers(
+			testserver.CombineHandlers(
+				testserver.VerifyRequest("POST", "/sky/issuer/token"),
+				testserver.VerifyBasicAuth("fly", "Zmx5"),
+				testserver.VerifyFormKV("grant_type", "password"),
+				testserver.VerifyFormKV("username", "some-username"),
+				testserver.VerifyFormKV("password", "some-password"),
+				testserver.RespondWith(http.StatusOK, `{"token_type":"bearer","access_token":"some-access-token","id_token":"some-id-token"}`),
+			),
+		)
+
+		_, err := apiCommand.Login(server.URL(), teamName, fly.Credentials{Username: "some-username", Password: "some-password"}, false)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Describe("Login", func() {
+		It("requests a token from the token endpoint", func() {
+			login()
+
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		Context("when the issuer token endpoint does not exist", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("POST", "/sky/issuer/token"),
+						testserver.RespondWith(http.StatusNotFound, ""),
+					),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("POST", "/sky/token"),
+						testserver.RespondWith(http.StatusOK, `{"token_type":"bearer","access_token":"some-access-token"}`),
+					),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines"),
+						testserver.VerifyHeaderKV("Authorization", "bearer some-access-token"),
+						testserver.RespondWith(http.StatusOK, `[]`),
+					),
+				)
+			})
+
+			It("falls back to the legacy token endpoint", func() {
+				_, err := apiCommand.Login(server.URL(), teamName, fly.Credentials{Username: "some-username", Password: "some-password"}, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = apiCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when logged in to multiple teams", func() {
+			BeforeEach(func() {
+				login()
+
+				server.AppendHandlers(
+					testserver.RespondWith(http.StatusOK, `{"token_type":"bearer","id_token":"other-id-token"}`),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines"),
+						testserver.VerifyHeaderKV("Authorization", "bearer some-id-token"),
+						testserver.RespondWith(http.StatusOK, `[]`),
+					),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/other-team/pipelines"),
+						testserver.VerifyHeaderKV("Authorization", "bearer other-id-token"),
+						testserver.RespondWith(http.StatusOK, `[]`),
+					),
+				)
+			})
+
+			It("uses the token of each team", func() {
+				_, err := apiCommand.Login(server.URL(), "other-team", fly.Credentials{Username: "other-username", Password: "other-password"}, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = apiCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = apiCommand.Pipelines("other-team")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when a token is specified", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines"),
+						testserver.VerifyHeaderKV("Authorization", "Bearer some-token"),
+						testserver.RespondWith(http.StatusOK, `[]`),
+					),
+				)
+			})
+
+			It("uses the token without requesting one", func() {
+				_, err := apiCommand.Login(server.URL(), teamName, fly.Credentials{Token: "some-token"}, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = apiCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when client credentials are specified", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("POST", "/sky/issuer/token"),
+						testserver.VerifyBasicAuth("some-client", "some-secret"),
+						testserver.VerifyFormKV("grant_type", "client_credentials"),
+						testserver.RespondWith(http.StatusOK, `{"token_type":"bearer","access_token":"some-access-token"}`),
+					),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines"),
+						testserver.VerifyHeaderKV("Authorization", "bearer some-access-token"),
+						testserver.RespondWith(http.StatusOK, `[]`),
+					),
+				)
+			})
+
+			It("requests a token with the client credentials grant", func() {
+				credentials := fly.Credentials{
+					ClientID:     "some-client",
+					ClientSecret: "some-secret",
+				}
+
+				_, err := apiCommand.Login(server.URL(), teamName, credentials, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = apiCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("redacts the token", func() {
+				credentials := fly.Credentials{
+					ClientID:     "some-client",
+					ClientSecret: "some-secret",
+				}
+
+				_, err := apiCommand.Login(server.URL(), teamName, credentials, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(secrets.Redact("token: some-access-token")).To(Equal("token: ***REDACTED-TOKEN-TEAM-main***"))
+			})
+		})
+
+		Context("when no username or password is specified", func() {
+			It("does not request a token", func() {
+				_, err := apiCommand.Login(server.URL(), teamName, fly.Credentials{}, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(server.ReceivedRequests()).To(BeEmpty())
+			})
+		})
+
+		Context("when teams with different insecure settings are logged in to concurrently", func() {
+			var (
+				tlsServer *httptest.Server
+			)
+
+			BeforeEach(func() {
+				tlsServer = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte(`[{"name":"insecure-pipeline"}]`))
+				}))
+
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines"),
+						testserver.VerifyHeaderKV("Authorization", "Bearer main-token"),
+						testserver.RespondWith(http.StatusOK, `[{"name":"main-pipeline"}]`),
+					),
+				)
+			})
+
+			AfterEach(func() {
+				tlsServer.Close()
+			})
+
+			It("keeps the URL and TLS settings of each team separate", func() {
+				var wg sync.WaitGroup
+				wg.Add(2)
+
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					_, err := apiCommand.Login(server.URL(), teamName, fly.Credentials{Token: "main-token"}, false)
+					Expect(err).NotTo(HaveOccurred())
+				}()
+
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					_, err := apiCommand.Login(tlsServer.URL, "insecure-team", fly.Credentials{Token: "insecure-token"}, true)
+					Expect(err).NotTo(HaveOccurred())
+				}()
+
+				wg.Wait()
+
+				pipelines, err := apiCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pipelines).To(Equal([]string{"main-pipeline"}))
+
+				pipelines, err = apiCommand.Pipelines("insecure-team")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pipelines).To(Equal([]string{"insecure-pipeline"}))
+			})
+		})
+
+		Context("when the credentials are rejected", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.RespondWith(http.StatusUnauthorized, "invalid credentials"),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := apiCommand.Login(server.URL(), teamName, fly.Credentials{Username: "some-username", Password: "some-password"}, false)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*401.*invalid credentials"))
+			})
+		})
+	})
+
+	Describe("Cleanup", func() {
+		It("forgets the session of every team", func() {
+			login()
+
+			apiCommand.Cleanup()
+
+			_, err := apiCommand.Pipelines(teamName)
+			Expect(err).To(MatchError(ContainSubstring("login to team 'main' must be performed")))
+		})
+	})
+
+	Describe("Pipelines", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines"),
+					testserver.VerifyHeaderKV("Authorization", "bearer some-id-token"),
+					testserver.RespondWith(http.StatusOK, `[{"name":"abc"},{"name":"def"}]`),
+				),
+			)
+		})
+
+		It("returns pipelines without error", func() {
+			pipelines, err := apiCommand.Pipelines(teamName)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(pipelines).To(Equal([]string{"abc", "def"}))
+		})
+
+		Context("when there are instanced pipelines", func() {
+			BeforeEach(func() {
+				server.SetHandler(1, testserver.RespondWith(http.StatusOK, `[{"name":"abc","instance_vars":{"branch":"main"}}]`))
+			})
+
+			It("returns references including the instance vars", func() {
+				pipelines, err := apiCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(pipelines).To(Equal([]string{"abc/branch:main"}))
+			})
+		})
+
+		Context("when there are archived pipelines", func() {
+			BeforeEach(func() {
+				server.SetHandler(1, testserver.RespondWith(http.StatusOK, `[{"name":"abc"},{"name":"def","archived":true}]`))
+			})
+
+			It("leaves them out, as fly does", func() {
+				pipelines, err := apiCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(pipelines).To(Equal([]string{"abc"}))
+			})
+		})
+	})
+
+	Describe("PipelineStates", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines"),
+					testserver.RespondWith(http.StatusOK, `[{"name":"abc","paused":true},{"name":"def","public":true,"archived":true}]`),
+				),
+			)
+		})
+
+		It("returns the state of each pipeline", func() {
+			states, err := apiCommand.PipelineStates(teamName)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(states).To(Equal([]fly.PipelineState{
+				{Name: "abc", Paused: true},
+				{Name: "def", Public: true, Archived: true},
+			}))
+		})
+	})
+
+	Describe("Jobs", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines/abc/jobs"),
+					testserver.RespondWith(http.StatusOK, `[{"name":"build","next_build":{"id":2,"name":"4","status":"started","start_time":30}}]`),
+				),
+			)
+		})
+
+		It("returns the jobs of the pipeline", func() {
+			jobs, err := apiCommand.Jobs(teamName, "abc")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jobs).To(Equal([]fly.Job{
+				{
+					Name:      "build",
+					NextBuild: &fly.Build{ID: 2, Name: "4", Status: "started", StartTime: 30},
+				},
+			}))
+		})
+	})
+
+	Describe("GetPipeline", func() {
+		BeforeEach(func() {
+			login()
+		})
+
+		Context("when the pipeline exists", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines/some-pipeline/config"),
+						testserver.RespondWith(http.StatusOK, `{"config":{"jobs":[{"name":"some-job"}]}}`),
+					),
+				)
+			})
+
+			It("returns the config as YAML, as fly does", func() {
+				output, err := apiCommand.GetPipeline(teamName, "some-pipeline")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(string(output)).To(Equal("jobs:\n- name: some-job\n"))
+			})
+		})
+
+		Context("when the pipeline does not exist", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.RespondWith(http.StatusNotFound, ""),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := apiCommand.GetPipeline(teamName, "some-pipeline")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the pipeline is instanced", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines/some-pipeline/config", `vars=%7B%22branch%22%3A%22main%22%7D`),
+						testserver.RespondWith(http.StatusOK, `{"config":{}}`),
+					),
+				)
+			})
+
+			It("identifies the pipeline by its instance vars", func() {
+				_, err := apiCommand.GetPipeline(teamName, "some-pipeline/branch:main")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("SetPipeline", func() {
+		var (
+			tempDir        string
+			configFilepath string
+		)
+
+		BeforeEach(func() {
+			login()
+
+			var err error
+			tempDir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			configFilepath = filepath.Join(tempDir, "pipeline.yml")
+			err = ioutil.WriteFile(configFilepath, []byte("jobs:\n- name: ((job-name))\n"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			err := os.RemoveAll(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the pipeline exists", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/api/v1/teams/main/pipelines/some-pipeline/config"),
+						testserver.RespondWith(http.StatusOK, `{"config":{}}`, http.Header{
+							"X-Concourse-Config-Version": {"42"},
+						}),
+					),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/config"),
+						testserver.VerifyHeaderKV("X-Concourse-Config-Version", "42"),
+						testserver.VerifyHeaderKV("Content-Type", "application/x-yaml"),
+						testserver.VerifyBody([]byte("jobs:\n- name: some-job\n")),
+						testserver.RespondWith(http.StatusOK, `{"warnings":[{"message":"some warning"}]}`),
+					),
+				)
+			})
+
+			It("interpolates vars and sets the config with the current version", func() {
+				output, err := apiCommand.SetPipeline(
+					teamName,
+					"some-pipeline",
+					configFilepath,
+					nil,
+					map[string]interface{}{"job-name": "some-job"},
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(string(output)).To(ContainSubstring("some warning"))
+			})
+		})
+
+		Context("when the pipeline does not exist", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.RespondWith(http.StatusNotFound, ""),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/config"),
+						func(w http.ResponseWriter, req *http.Request) {
+							Expect(req.Header.Get("X-Concourse-Config-Version")).To(BeEmpty())
+						},
+						testserver.RespondWith(http.StatusCreated, ""),
+					),
+				)
+			})
+
+			It("creates the pipeline", func() {
+				_, err := apiCommand.SetPipeline(teamName, "some-pipeline", configFilepath, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when the pipeline is instanced", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.RespondWith(http.StatusNotFound, ""),
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/config", `vars=%7B%22job-name%22%3A%22instance-job%22%7D`),
+						testserver.VerifyBody([]byte("jobs:\n- name: instance-job\n")),
+						testserver.RespondWith(http.StatusCreated, ""),
+					),
+				)
+			})
+
+			It("interpolates the instance vars, which take precedence over vars", func() {
+				_, err := apiCommand.SetPipeline(
+					teamName,
+					"some-pipeline/job-name:instance-job",
+					configFilepath,
+					nil,
+					map[string]interface{}{"job-name": "some-job"},
+				)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when setting the config fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.RespondWith(http.StatusNotFound, ""),
+					testserver.RespondWith(http.StatusBadRequest, "invalid config"),
+				)
+			})
+
+			It("returns an error", func() {
+				_, err := apiCommand.SetPipeline(teamName, "some-pipeline", configFilepath, nil, nil)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*400.*invalid config"))
+			})
+		})
+	})
+
+	Describe("DestroyPipeline", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("DELETE", "/api/v1/teams/main/pipelines/some-pipeline"),
+					testserver.RespondWith(http.StatusNoContent, ""),
+				),
+			)
+		})
+
+		It("destroys the pipeline", func() {
+			_, err := apiCommand.DestroyPipeline(teamName, "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("UnpausePipeline", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/unpause"),
+					testserver.RespondWith(http.StatusOK, ""),
+				),
+			)
+		})
+
+		It("unpauses the pipeline", func() {
+			_, err := apiCommand.UnpausePipeline(teamName, "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("PausePipeline", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/pause"),
+					testserver.RespondWith(http.StatusOK, ""),
+				),
+			)
+		})
+
+		It("pauses the pipeline", func() {
+			_, err := apiCommand.PausePipeline(teamName, "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ArchivePipeline", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/archive"),
+					testserver.RespondWith(http.StatusOK, ""),
+				),
+			)
+		})
+
+		It("archives the pipeline", func() {
+			_, err := apiCommand.ArchivePipeline(teamName, "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ExposePipeline", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/expose"),
+					testserver.RespondWith(http.StatusOK, ""),
+				),
+			)
+		})
+
+		It("exposes the pipeline", func() {
+			_, err := apiCommand.ExposePipeline(teamName, "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("HidePipeline", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/some-pipeline/hide"),
+					testserver.RespondWith(http.StatusOK, ""),
+				),
+			)
+		})
+
+		It("hides the pipeline", func() {
+			_, err := apiCommand.HidePipeline(teamName, "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("RenamePipeline", func() {
+		BeforeEach(func() {
+			login()
+
+			server.AppendHandlers(
+				testserver.CombineHandlers(
+					testserver.VerifyRequest("PUT", "/api/v1/teams/main/pipelines/old-pipeline/rename"),
+					testserver.VerifyJSON(`{"name":"new-pipeline"}`),
+					testserver.RespondWith(http.StatusNoContent, ""),
+				),
+			)
+		})
+
+		It("renames the pipeline", func() {
+			_, err := apiCommand.RenamePipeline(teamName, "old-pipeline", "new-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when login has not been performed", func() {
+		It("returns an error", func() {
+			_, err := apiCommand.Pipelines(teamName)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,30 @@
+package fly
+
+import "github.com/concourse/concourse-pipeline-resource/concourse"
+
+// Credentials authenticate a session with a team. At most one of a username
+// and password, a token, or a client ID and secret is expected; if none are
+// provided the team must have no authentication configured.
+type Credentials struct {
+	Username string
+	Password string
+
+	// Token is a pre-issued bearer token.
+	Token string
+
+	// ClientID and ClientSecret are exchanged for a token via the OAuth2
+	// client credentials grant.
+	ClientID     string
+	ClientSecret string
+}
+
+// TeamCredentials returns the credentials configured for a team in source.
+func TeamCredentials(team concourse.Team) Credentials {
+	return Credentials{
+		Username:     team.Username,
+		Password:     team.Password,
+		Token:        team.Token,
+		ClientID:     team.ClientID,
+		ClientSecret: team.ClientSecret,
+	}
+}
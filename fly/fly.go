@@ -2,124 +2,348 @@ package fly
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sync"
 
 	"crypto/tls"
 	"net/http"
 
+	"github.com/concourse/concourse-pipeline-resource/concourse"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	"gopkg.in/yaml.v2"
 )
 
 //go:generate counterfeiter . Command
 
+// Command performs operations against a Concourse. Pipelines are identified
+// by their references, as formatted by concourse.PipelineRef, which include
+// the instance vars of instanced pipelines. Each team must be logged
+// in to before operating on its pipelines. Each team has its own session,
+// so teams may be logged in to concurrently, and once a team is logged in
+// to, operations on its pipelines may be performed concurrently, including
+// with those of other teams.
 type Command interface {
-	Login(url string, teamName string, username string, password string, insecure bool) ([]byte, error)
-	Pipelines() ([]string, error)
-	GetPipeline(pipelineName string) ([]byte, error)
-	SetPipeline(pipelineName string, configFilepath string, varsFilepaths []string, vars map[string]interface{}) ([]byte, error)
-	DestroyPipeline(pipelineName string) ([]byte, error)
-	UnpausePipeline(pipelineName string) ([]byte, error)
-	ExposePipeline(pipelineName string) ([]byte, error)
+	Login(url string, teamName string, credentials Credentials, insecure bool) ([]byte, error)
+	Pipelines(teamName string) ([]string, error)
+	GetPipeline(teamName string, pipelineName string) ([]byte, error)
+	SetPipeline(teamName string, pipelineName string, configFilepath string, varsFilepaths []string, vars map[string]interface{}) ([]byte, error)
+	DestroyPipeline(teamName string, pipelineName string) ([]byte, error)
+	UnpausePipeline(teamName string, pipelineName string) ([]byte, error)
+	PausePipeline(teamName string, pipelineName string) ([]byte, error)
+	ArchivePipeline(teamName string, pipelineName string) ([]byte, error)
+	ExposePipeline(teamName string, pipelineName string) ([]byte, error)
+	HidePipeline(teamName string, pipelineName string) ([]byte, error)
+	RenamePipeline(teamName string, oldName string, newName string) ([]byte, error)
+	PipelineStates(teamName string) ([]PipelineState, error)
+	Jobs(teamName string, pipelineName string) ([]Job, error)
+
+	// Cleanup removes the sessions of every team, which must not be operated
+	// on afterwards.
+	Cleanup()
+}
+
+// PipelineState is the state of a pipeline, including archived pipelines.
+type PipelineState struct {
+	Name         string                 `json:"name"`
+	InstanceVars map[string]interface{} `json:"instance_vars,omitempty"`
+	Paused       bool                   `json:"paused"`
+	Public       bool                   `json:"public"`
+	Archived     bool                   `json:"archived"`
+
+	// LastUpdated is when the config of the pipeline was last set, in
+	// seconds since the epoch.
+	LastUpdated int64 `json:"last_updated,omitempty"`
+}
+
+// Job is a job of a pipeline, with its latest finished build and the build
+// which is pending or running, if any.
+type Job struct {
+	Name          string `json:"name"`
+	Paused        bool   `json:"paused,omitempty"`
+	FinishedBuild *Build `json:"finished_build,omitempty"`
+	NextBuild     *Build `json:"next_build,omitempty"`
+}
+
+// Build is a build of a job. Times are in seconds since the epoch, and are
+// zero until the build has started or ended.
+type Build struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	StartTime int64  `json:"start_time,omitempty"`
+	EndTime   int64  `json:"end_time,omitempty"`
+}
+
+// Ref returns the reference to the pipeline, which identifies it in the
+// operations of a Command.
+func (s PipelineState) Ref() string {
+	return concourse.PipelineRef{Name: s.Name, InstanceVars: s.InstanceVars}.String()
+}
+
+// pipelineRefs returns the references to the pipelines in the JSON output
+// of `fly pipelines`, or of the API.
+func pipelineRefs(b []byte) ([]string, error) {
+	var ps []PipelineState
+
+	err := json.Unmarshal(b, &ps)
+	if err != nil {
+		return nil, err
+	}
+
+	// fly only lists archived pipelines if asked to, but the API always
+	// lists them.
+	refs := make([]string, 0, len(ps))
+	for _, p := range ps {
+		if !p.Archived {
+			refs = append(refs, p.Ref())
+		}
+	}
+
+	return refs, nil
 }
 
 type command struct {
 	target        string
 	logger        logger.Logger
+	secrets       *redact.Secrets
 	flyBinaryPath string
+
+	homeOnce sync.Once
+	homeRoot string
+	homeErr  error
+
+	// fly sync replaces the binary, which is shared by every team, so it is
+	// run once, while no other fly command is running.
+	binaryMu sync.RWMutex
+	syncOnce sync.Once
+	syncOut  []byte
+	syncErr  error
 }
 
-func NewCommand(target string, logger logger.Logger, flyBinaryPath string) Command {
+// NewCommand returns a Command which invokes the fly binary. Tokens obtained
+// when logging in are added to secrets.
+func NewCommand(target string, logger logger.Logger, secrets *redact.Secrets, flyBinaryPath string) Command {
 	return &command{
 		target:        target,
 		logger:        logger,
+		secrets:       secrets,
 		flyBinaryPath: flyBinaryPath,
 	}
 }
 
-func (f command) Login(
+func (f *command) Login(
 	url string,
 	teamName string,
-	username string,
-	password string,
+	credentials Credentials,
 	insecure bool,
 ) ([]byte, error) {
-	args := []string{
-		"login",
-		"-c", url,
-		"-n", teamName,
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+		Proxy:           http.ProxyFromEnvironment,
 	}
 
-	if username != "" && password != "" {
-		args = append(args, "-u", username, "-p", password)
-	}
+	var loginOut []byte
+	var err error
+
+	if credentials.Token != "" || credentials.ClientID != "" {
+		// fly can only log in interactively with a token, so the target is
+		// saved directly instead.
+		tokenType := "bearer"
+		token := credentials.Token
+
+		if token == "" {
+			tokenType, token, err = requestToken(f.logger, f.secrets, &http.Client{Transport: tr}, url, teamName, credentials)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		loginOut, err = f.saveTarget(url, teamName, tokenType, token, insecure)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		args := []string{
+			"login",
+			"-c", url,
+			"-n", teamName,
+		}
+
+		if credentials.Username != "" && credentials.Password != "" {
+			args = append(args, "-u", credentials.Username, "-p", credentials.Password)
+		}
+
+		if insecure {
+			args = append(args, "-k")
+		}
 
-	if insecure {
-		args = append(args, "-k")
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			Proxy:           http.ProxyFromEnvironment,
+		loginOut, err = f.run(teamName, args...)
+		if err != nil {
+			return nil, err
 		}
-		http.DefaultClient.Transport = tr
 	}
 
-	loginOut, err := f.run(args...)
+	syncOut, err := f.sync(teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(loginOut, syncOut...), nil
+}
+
+// sync runs fly sync with the target of the first team logged in to,
+// returning its output only to that login.
+func (f *command) sync(teamName string) ([]byte, error) {
+	var out []byte
+
+	f.syncOnce.Do(func() {
+		f.binaryMu.Lock()
+		defer f.binaryMu.Unlock()
+
+		f.syncOut, f.syncErr = f.execute(teamName, "sync")
+		out = f.syncOut
+	})
+
+	return out, f.syncErr
+}
+
+type flyrc struct {
+	Targets map[string]flyrcTarget `yaml:"targets"`
+}
+
+type flyrcTarget struct {
+	API      string     `yaml:"api"`
+	Team     string     `yaml:"team"`
+	Insecure bool       `yaml:"insecure,omitempty"`
+	Token    flyrcToken `yaml:"token"`
+}
+
+type flyrcToken struct {
+	Type  string `yaml:"type"`
+	Value string `yaml:"value"`
+}
+
+// saveTarget writes the .flyrc of the team, as `fly login` would on success.
+func (f *command) saveTarget(
+	url string,
+	teamName string,
+	tokenType string,
+	token string,
+	insecure bool,
+) ([]byte, error) {
+	home, err := f.homeDir(teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := flyrc{
+		Targets: map[string]flyrcTarget{
+			f.target: {
+				API:      url,
+				Team:     teamName,
+				Insecure: insecure,
+				Token: flyrcToken{
+					Type:  tokenType,
+					Value: token,
+				},
+			},
+		},
+	}
+
+	b, err := yaml.Marshal(rc)
 	if err != nil {
+		// Untested as the flyrc always marshals to YAML
 		return nil, err
 	}
 
-	syncOut, err := f.run("sync")
+	f.logger.Debugf("Saving target for team: %s\n", teamName)
+	err = ioutil.WriteFile(filepath.Join(home, ".flyrc"), b, 0600)
 	if err != nil {
 		return nil, err
 	}
 
-	return append(loginOut, syncOut...), nil
+	return []byte("target saved\n"), nil
 }
 
-func (f command) Pipelines() ([]string, error) {
-	psOut, err := f.run("pipelines", "--json")
+func (f *command) Pipelines(teamName string) ([]string, error) {
+	psOut, err := f.run(teamName, "pipelines", "--json")
 	if err != nil {
 		return nil, err
 	}
 
-	var ps []struct {
-		Name string `json:"name"`
+	return pipelineRefs(psOut)
+}
+
+func (f *command) PipelineStates(teamName string) ([]PipelineState, error) {
+	psOut, err := f.run(teamName, "pipelines", "--json", "--include-archived")
+	if err != nil {
+		return nil, err
 	}
 
+	var ps []PipelineState
+
 	err = json.Unmarshal(psOut, &ps)
 	if err != nil {
 		return nil, err
 	}
 
-	names := make([]string, len(ps))
-	for i, p := range ps {
-		names[i] = p.Name
+	return ps, nil
+}
+
+func (f *command) Jobs(teamName string, pipelineName string) ([]Job, error) {
+	jobsOut, err := f.run(teamName, "jobs", "-p", pipelineName, "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []Job
+
+	err = json.Unmarshal(jobsOut, &jobs)
+	if err != nil {
+		return nil, err
 	}
 
-	return names, nil
+	return jobs, nil
 }
 
-func (f command) GetPipeline(pipelineName string) ([]byte, error) {
+func (f *command) GetPipeline(teamName string, pipelineName string) ([]byte, error) {
 	return f.run(
+		teamName,
 		"get-pipeline",
 		"-p", pipelineName,
 	)
 }
 
-func (f command) SetPipeline(
+func (f *command) SetPipeline(
+	teamName string,
 	pipelineName string,
 	configFilepath string,
 	varsFilepaths []string,
 	vars map[string]interface{},
 ) ([]byte, error) {
+	ref, err := concourse.ParsePipelineRef(pipelineName)
+	if err != nil {
+		return nil, err
+	}
+
 	allArgs := []string{
 		"set-pipeline",
 		"-n",
-		"-p", pipelineName,
+		"-p", ref.Name,
 		"-c", configFilepath,
 	}
 
+	for _, iv := range concourse.FlattenInstanceVars(ref.InstanceVars) {
+		allArgs = append(allArgs, "-i", fmt.Sprintf("%s=%s", iv.Key, iv.Value))
+	}
+
 	for _, vf := range varsFilepaths {
 		allArgs = append(allArgs, "-l", vf)
 	}
@@ -134,41 +358,131 @@ func (f command) SetPipeline(
 		allArgs = append(allArgs, "-y", fmt.Sprintf("%s=%s", key, payload))
 	}
 
-	return f.run(allArgs...)
+	return f.run(teamName, allArgs...)
 }
 
-func (f command) UnpausePipeline(pipelineName string) ([]byte, error) {
+func (f *command) UnpausePipeline(teamName string, pipelineName string) ([]byte, error) {
 	return f.run(
+		teamName,
 		"unpause-pipeline",
 		"-p", pipelineName,
 	)
 }
 
-func (f command) DestroyPipeline(pipelineName string) ([]byte, error) {
+func (f *command) PausePipeline(teamName string, pipelineName string) ([]byte, error) {
+	return f.run(
+		teamName,
+		"pause-pipeline",
+		"-p", pipelineName,
+	)
+}
+
+func (f *command) ArchivePipeline(teamName string, pipelineName string) ([]byte, error) {
+	return f.run(
+		teamName,
+		"archive-pipeline",
+		"-n",
+		"-p", pipelineName,
+	)
+}
+
+func (f *command) DestroyPipeline(teamName string, pipelineName string) ([]byte, error) {
 	return f.run(
+		teamName,
 		"destroy-pipeline",
 		"-n",
 		"-p", pipelineName,
 	)
 }
 
-func (f command) ExposePipeline(pipelineName string) ([]byte, error) {
+func (f *command) ExposePipeline(teamName string, pipelineName string) ([]byte, error) {
 	return f.run(
+		teamName,
 		"expose-pipeline",
 		"-p", pipelineName,
 	)
 }
 
-func (f command) run(args ...string) ([]byte, error) {
+func (f *command) HidePipeline(teamName string, pipelineName string) ([]byte, error) {
+	return f.run(
+		teamName,
+		"hide-pipeline",
+		"-p", pipelineName,
+	)
+}
+
+func (f *command) RenamePipeline(teamName string, oldName string, newName string) ([]byte, error) {
+	return f.run(
+		teamName,
+		"rename-pipeline",
+		"-o", oldName,
+		"-n", newName,
+	)
+}
+
+// Cleanup removes the HOME directories of every team, including the .flyrc
+// files holding their tokens.
+func (f *command) Cleanup() {
+	f.homeOnce.Do(func() {})
+	if f.homeRoot == "" {
+		return
+	}
+
+	err := os.RemoveAll(f.homeRoot)
+	if err != nil {
+		f.logger.Warnf("Failed to remove fly configuration: %v\n", err)
+	}
+}
+
+// homeDir returns the HOME directory for fly invocations against the team.
+// Each team has its own directory, and so its own .flyrc, so that sessions
+// for different teams never overwrite each other, and the .flyrc of the user
+// running the resource is never touched. The directory is named by a hash of
+// the team name, which may contain characters which are not valid in paths.
+func (f *command) homeDir(teamName string) (string, error) {
+	f.homeOnce.Do(func() {
+		f.homeRoot, f.homeErr = ioutil.TempDir("", "concourse-pipeline-resource-fly")
+	})
+	if f.homeErr != nil {
+		return "", f.homeErr
+	}
+
+	dir := filepath.Join(f.homeRoot, fmt.Sprintf("%x", sha256.Sum256([]byte(teamName))))
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func (f *command) run(teamName string, args ...string) ([]byte, error) {
+	f.binaryMu.RLock()
+	defer f.binaryMu.RUnlock()
+
+	return f.execute(teamName, args...)
+}
+
+func (f *command) execute(teamName string, args ...string) ([]byte, error) {
 	if f.target == "" {
 		return nil, fmt.Errorf("target cannot be empty in command.run")
 	}
 
+	if teamName == "" {
+		return nil, fmt.Errorf("team name cannot be empty in command.run")
+	}
+
+	home, err := f.homeDir(teamName)
+	if err != nil {
+		return nil, err
+	}
+
 	defaultArgs := []string{
 		"-t", f.target,
 	}
 	allArgs := append(defaultArgs, args...)
 	cmd := exec.Command(f.flyBinaryPath, allArgs...)
+	cmd.Env = append(os.Environ(), "HOME="+home)
 
 	outbuf := bytes.NewBuffer(nil)
 	errbuf := bytes.NewBuffer(nil)
@@ -177,7 +491,7 @@ func (f command) run(args ...string) ([]byte, error) {
 	cmd.Stderr = errbuf
 
 	f.logger.Debugf("Starting fly command: %v\n", allArgs)
-	err := cmd.Start()
+	err = cmd.Start()
 	if err != nil {
 		// If the command was never started, there will be nothing in the buffers
 		return nil, err
@@ -3,11 +3,16 @@ package fly_test
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/concourse/concourse-pipeline-resource/fly"
+	"github.com/concourse/concourse-pipeline-resource/internal/testserver"
 	"github.com/concourse/concourse-pipeline-resource/logger/loggerfakes"
+	"github.com/concourse/concourse-pipeline-resource/redact"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -54,7 +59,7 @@ var _ = Describe("Command", func() {
 		err := ioutil.WriteFile(flyBinaryPath, []byte(fakeFlyContents), os.ModePerm)
 		Expect(err).NotTo(HaveOccurred())
 
-		flyCommand = fly.NewCommand(target, fakeLogger, flyBinaryPath)
+		flyCommand = fly.NewCommand(target, fakeLogger, redact.NewSecrets(), flyBinaryPath)
 	})
 
 	AfterEach(func() {
@@ -78,7 +83,7 @@ var _ = Describe("Command", func() {
 		})
 
 		It("returns output without error", func() {
-			output, err := flyCommand.Login(url, teamName, username, password, insecure)
+			output, err := flyCommand.Login(url, teamName, fly.Credentials{Username: username, Password: password}, insecure)
 			Expect(err).NotTo(HaveOccurred())
 
 			expectedOutput := fmt.Sprintf(
@@ -102,7 +107,7 @@ var _ = Describe("Command", func() {
 			})
 
 			It("adds -k flag to command", func() {
-				output, err := flyCommand.Login(url, teamName, username, password, insecure)
+				output, err := flyCommand.Login(url, teamName, fly.Credentials{Username: username, Password: password}, insecure)
 				Expect(err).NotTo(HaveOccurred())
 
 				expectedOutput := fmt.Sprintf(
@@ -122,13 +127,68 @@ var _ = Describe("Command", func() {
 			})
 		})
 
+		Context("when teams with different insecure settings are logged in to concurrently", func() {
+			It("passes -k only for the insecure team", func() {
+				outputs := make([]string, 2)
+
+				var wg sync.WaitGroup
+				for i, team := range []string{"secure-team", "insecure-team"} {
+					wg.Add(1)
+
+					go func(i int, team string) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						output, err := flyCommand.Login(url, team, fly.Credentials{Username: username, Password: password}, i == 1)
+						Expect(err).NotTo(HaveOccurred())
+
+						outputs[i] = string(output)
+					}(i, team)
+				}
+				wg.Wait()
+
+				Expect(outputs[0]).To(ContainSubstring("-n secure-team -u %s -p %s\n", username, password))
+				Expect(outputs[1]).To(ContainSubstring("-n insecure-team -u %s -p %s -k\n", username, password))
+			})
+
+			Context("when fly is synced", func() {
+				BeforeEach(func() {
+					fakeFlyContents = fmt.Sprintf(`#!/bin/sh
+echo $@
+echo $@ >> %s`, filepath.Join(tempDir, "invocations"))
+				})
+
+				It("syncs it once", func() {
+					var wg sync.WaitGroup
+					for _, team := range []string{"team-1", "team-2", "team-3"} {
+						wg.Add(1)
+
+						go func(team string) {
+							defer GinkgoRecover()
+							defer wg.Done()
+
+							_, err := flyCommand.Login(url, team, fly.Credentials{Username: username, Password: password}, insecure)
+							Expect(err).NotTo(HaveOccurred())
+						}(team)
+					}
+					wg.Wait()
+
+					invocations, err := ioutil.ReadFile(filepath.Join(tempDir, "invocations"))
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(strings.Count(string(invocations), " login ")).To(Equal(3))
+					Expect(strings.Count(string(invocations), " sync\n")).To(Equal(1))
+				})
+			})
+		})
+
 		Context("when there is an error starting the commmand", func() {
 			BeforeEach(func() {
 				fakeFlyContents = ""
 			})
 
 			It("returns an error", func() {
-				_, err := flyCommand.Login(url, teamName, username, password, insecure)
+				_, err := flyCommand.Login(url, teamName, fly.Credentials{Username: username, Password: password}, insecure)
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -140,7 +200,7 @@ var _ = Describe("Command", func() {
 			})
 
 			It("does not pass the `p` or `u` flags to fly", func() {
-				output, err := flyCommand.Login(url, teamName, username, password, insecure)
+				output, err := flyCommand.Login(url, teamName, fly.Credentials{Username: username, Password: password}, insecure)
 				Expect(err).NotTo(HaveOccurred())
 
 				expectedOutput := fmt.Sprintf(
@@ -157,13 +217,72 @@ var _ = Describe("Command", func() {
 			})
 		})
 
+		Context("when a token is specified", func() {
+			BeforeEach(func() {
+				fakeFlyContents = `#!/bin/sh
+echo $@
+cat $HOME/.flyrc`
+			})
+
+			It("saves the target with the token instead of invoking fly login", func() {
+				output, err := flyCommand.Login(url, teamName, fly.Credentials{Token: "some-token"}, insecure)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(string(output)).NotTo(ContainSubstring("login"))
+				Expect(string(output)).To(HavePrefix("target saved\n-t %s sync\n", target))
+				Expect(string(output)).To(ContainSubstring("api: %s", url))
+				Expect(string(output)).To(ContainSubstring("team: %s", teamName))
+				Expect(string(output)).To(ContainSubstring("type: bearer"))
+				Expect(string(output)).To(ContainSubstring("value: some-token"))
+			})
+		})
+
+		Context("when client credentials are specified", func() {
+			var (
+				server *testserver.Server
+			)
+
+			BeforeEach(func() {
+				server = testserver.NewServer()
+				url = server.URL()
+
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("POST", "/sky/issuer/token"),
+						testserver.VerifyBasicAuth("some-client", "some-secret"),
+						testserver.VerifyFormKV("grant_type", "client_credentials"),
+						testserver.RespondWith(http.StatusOK, `{"token_type":"bearer","access_token":"some-access-token"}`),
+					),
+				)
+
+				fakeFlyContents = `#!/bin/sh
+cat $HOME/.flyrc`
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("saves the target with a token requested from the token endpoint", func() {
+				credentials := fly.Credentials{
+					ClientID:     "some-client",
+					ClientSecret: "some-secret",
+				}
+
+				output, err := flyCommand.Login(url, teamName, credentials, insecure)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(string(output)).To(ContainSubstring("value: some-access-token"))
+			})
+		})
+
 		Context("when the command returns an error", func() {
 			BeforeEach(func() {
 				fakeFlyContents = errScript
 			})
 
 			It("appends stderr to the error", func() {
-				_, err := flyCommand.Login(url, teamName, username, password, insecure)
+				_, err := flyCommand.Login(url, teamName, fly.Credentials{Username: username, Password: password}, insecure)
 				Expect(err).To(HaveOccurred())
 
 				Expect(err.Error()).To(MatchRegexp(".*some err output.*"))
@@ -171,6 +290,72 @@ var _ = Describe("Command", func() {
 		})
 	})
 
+	Context("when the team name is empty", func() {
+		It("returns an error", func() {
+			_, err := flyCommand.GetPipeline("", "some-pipeline")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("fly configuration", func() {
+		BeforeEach(func() {
+			fakeFlyContents = `#!/bin/sh
+echo -n $HOME`
+		})
+
+		It("isolates the fly configuration of each team", func() {
+			mainHome, err := flyCommand.GetPipeline(teamName, "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+
+			otherHome, err := flyCommand.GetPipeline("other-team", "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(mainHome).NotTo(BeEmpty())
+			Expect(string(mainHome)).NotTo(Equal(os.Getenv("HOME")))
+			Expect(mainHome).NotTo(Equal(otherHome))
+
+			sameHome, err := flyCommand.GetPipeline(teamName, "other-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sameHome).To(Equal(mainHome))
+
+			info, err := os.Stat(string(mainHome))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+
+			flyCommand.Cleanup()
+		})
+
+		It("does not use the team name as a path", func() {
+			home, err := flyCommand.GetPipeline("../other-team", "some-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Base(string(home))).To(MatchRegexp("^[0-9a-f]{64}$"))
+
+			flyCommand.Cleanup()
+		})
+
+		Describe("Cleanup", func() {
+			It("removes the fly configuration of every team", func() {
+				home, err := flyCommand.GetPipeline(teamName, "some-pipeline")
+				Expect(err).NotTo(HaveOccurred())
+
+				flyCommand.Cleanup()
+
+				_, err = os.Stat(string(home))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+
+				_, err = os.Stat(filepath.Dir(string(home)))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+
+			Context("when no team has been operated on", func() {
+				It("does nothing", func() {
+					flyCommand.Cleanup()
+				})
+			})
+		})
+	})
+
 	Describe("Pipelines", func() {
 		BeforeEach(func() {
 			fakeFlyContents = `#!/bin/sh
@@ -179,11 +364,67 @@ echo '[{"name":"abc"},{"name":"def"}]'
 		})
 
 		It("returns pipelines without error", func() {
-			pipelines, err := flyCommand.Pipelines()
+			pipelines, err := flyCommand.Pipelines(teamName)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(pipelines).To(Equal([]string{"abc", "def"}))
 		})
+
+		Context("when there are instanced pipelines", func() {
+			BeforeEach(func() {
+				fakeFlyContents = `#!/bin/sh
+echo '[{"name":"abc","instance_vars":{"branch":"main","pr":1}},{"name":"abc","instance_vars":{"branch":"feature/x"}}]'
+`
+			})
+
+			It("returns references including the instance vars", func() {
+				pipelines, err := flyCommand.Pipelines(teamName)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(pipelines).To(Equal([]string{"abc/branch:main,pr:1", `abc/branch:"feature/x"`}))
+			})
+		})
+	})
+
+	Describe("PipelineStates", func() {
+		BeforeEach(func() {
+			fakeFlyContents = `#!/bin/sh
+if [ "$5" != "--include-archived" ]; then exit 1; fi
+echo '[{"name":"abc","paused":true},{"name":"def","public":true,"archived":true}]'
+`
+		})
+
+		It("returns the state of each pipeline, including archived pipelines", func() {
+			states, err := flyCommand.PipelineStates(teamName)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(states).To(Equal([]fly.PipelineState{
+				{Name: "abc", Paused: true},
+				{Name: "def", Public: true, Archived: true},
+			}))
+		})
+	})
+
+	Describe("Jobs", func() {
+		BeforeEach(func() {
+			fakeFlyContents = `#!/bin/sh
+if [ "$3 $4 $5 $6" != "jobs -p abc --json" ]; then exit 1; fi
+echo '[{"name":"build","finished_build":{"id":1,"name":"3","status":"succeeded","start_time":10,"end_time":20}},{"name":"deploy","paused":true}]'
+`
+		})
+
+		It("returns the jobs of the pipeline", func() {
+			jobs, err := flyCommand.Jobs(teamName, "abc")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jobs).To(Equal([]fly.Job{
+				{
+					Name:          "build",
+					FinishedBuild: &fly.Build{ID: 1, Name: "3", Status: "succeeded", StartTime: 10, EndTime: 20},
+				},
+				{Name: "deploy", Paused: true},
+			}))
+		})
 	})
 
 	Describe("GetPipeline", func() {
@@ -196,7 +437,7 @@ echo '[{"name":"abc"},{"name":"def"}]'
 		})
 
 		It("returns output without error", func() {
-			output, err := flyCommand.GetPipeline(pipelineName)
+			output, err := flyCommand.GetPipeline(teamName, pipelineName)
 			Expect(err).NotTo(HaveOccurred())
 
 			expectedOutput := fmt.Sprintf(
@@ -222,7 +463,7 @@ echo '[{"name":"abc"},{"name":"def"}]'
 		})
 
 		It("returns output without error", func() {
-			output, err := flyCommand.SetPipeline(pipelineName, configFilepath, nil, nil)
+			output, err := flyCommand.SetPipeline(teamName, pipelineName, configFilepath, nil, nil)
 			Expect(err).NotTo(HaveOccurred())
 
 			expectedOutput := fmt.Sprintf(
@@ -237,6 +478,30 @@ echo '[{"name":"abc"},{"name":"def"}]'
 			Expect(string(output)).To(Equal(expectedOutput))
 		})
 
+		Context("when the pipeline is instanced", func() {
+			BeforeEach(func() {
+				pipelineName = "some-pipeline/branch:main,pr:1"
+			})
+
+			It("sets the pipeline with its instance vars", func() {
+				output, err := flyCommand.SetPipeline(teamName, pipelineName, configFilepath, nil, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedOutput := fmt.Sprintf(
+					"%s %s %s %s %s %s %s %s %s %s %s %s\n",
+					"-t", target,
+					"set-pipeline",
+					"-n",
+					"-p", "some-pipeline",
+					"-c", configFilepath,
+					"-i", "branch=main",
+					"-i", "pr=1",
+				)
+
+				Expect(string(output)).To(Equal(expectedOutput))
+			})
+		})
+
 		Context("when optional vars are provided", func() {
 
 			var (
@@ -254,7 +519,7 @@ echo '[{"name":"abc"},{"name":"def"}]'
 			})
 
 			It("returns output without error", func() {
-				output, err := flyCommand.SetPipeline(pipelineName, configFilepath, nil, vars)
+				output, err := flyCommand.SetPipeline(teamName, pipelineName, configFilepath, nil, vars)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(string(output)).To(HavePrefix("-t %s set-pipeline", target))
@@ -280,7 +545,7 @@ echo '[{"name":"abc"},{"name":"def"}]'
 			})
 
 			It("returns output without error", func() {
-				output, err := flyCommand.SetPipeline(pipelineName, configFilepath, varsFiles, nil)
+				output, err := flyCommand.SetPipeline(teamName, pipelineName, configFilepath, varsFiles, nil)
 				Expect(err).NotTo(HaveOccurred())
 
 				expectedOutput := fmt.Sprintf(
@@ -309,7 +574,7 @@ echo '[{"name":"abc"},{"name":"def"}]'
 		})
 
 		It("returns output without error", func() {
-			output, err := flyCommand.DestroyPipeline(pipelineName)
+			output, err := flyCommand.DestroyPipeline(teamName, pipelineName)
 			Expect(err).NotTo(HaveOccurred())
 
 			expectedOutput := fmt.Sprintf(
@@ -334,7 +599,7 @@ echo '[{"name":"abc"},{"name":"def"}]'
 		})
 
 		It("returns output without error", func() {
-			output, err := flyCommand.UnpausePipeline(pipelineName)
+			output, err := flyCommand.UnpausePipeline(teamName, pipelineName)
 			Expect(err).NotTo(HaveOccurred())
 
 			expectedOutput := fmt.Sprintf(
@@ -348,6 +613,55 @@ echo '[{"name":"abc"},{"name":"def"}]'
 		})
 	})
 
+	Describe("PausePipeline", func() {
+		var (
+			pipelineName string
+		)
+
+		BeforeEach(func() {
+			pipelineName = "some-pipeline"
+		})
+
+		It("returns output without error", func() {
+			output, err := flyCommand.PausePipeline(teamName, pipelineName)
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedOutput := fmt.Sprintf(
+				"%s %s %s %s %s\n",
+				"-t", target,
+				"pause-pipeline",
+				"-p", pipelineName,
+			)
+
+			Expect(string(output)).To(Equal(expectedOutput))
+		})
+	})
+
+	Describe("ArchivePipeline", func() {
+		var (
+			pipelineName string
+		)
+
+		BeforeEach(func() {
+			pipelineName = "some-pipeline"
+		})
+
+		It("returns output without error", func() {
+			output, err := flyCommand.ArchivePipeline(teamName, pipelineName)
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedOutput := fmt.Sprintf(
+				"%s %s %s %s %s %s\n",
+				"-t", target,
+				"archive-pipeline",
+				"-n",
+				"-p", pipelineName,
+			)
+
+			Expect(string(output)).To(Equal(expectedOutput))
+		})
+	})
+
 	Describe("ExposePipeline", func() {
 		var (
 			pipelineName string
@@ -358,7 +672,7 @@ echo '[{"name":"abc"},{"name":"def"}]'
 		})
 
 		It("returns output without error", func() {
-			output, err := flyCommand.ExposePipeline(pipelineName)
+			output, err := flyCommand.ExposePipeline(teamName, pipelineName)
 			Expect(err).NotTo(HaveOccurred())
 
 			expectedOutput := fmt.Sprintf(
@@ -371,4 +685,45 @@ echo '[{"name":"abc"},{"name":"def"}]'
 			Expect(string(output)).To(Equal(expectedOutput))
 		})
 	})
+
+	Describe("HidePipeline", func() {
+		var (
+			pipelineName string
+		)
+
+		BeforeEach(func() {
+			pipelineName = "some-pipeline"
+		})
+
+		It("returns output without error", func() {
+			output, err := flyCommand.HidePipeline(teamName, pipelineName)
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedOutput := fmt.Sprintf(
+				"%s %s %s %s %s\n",
+				"-t", target,
+				"hide-pipeline",
+				"-p", pipelineName,
+			)
+
+			Expect(string(output)).To(Equal(expectedOutput))
+		})
+	})
+
+	Describe("RenamePipeline", func() {
+		It("returns output without error", func() {
+			output, err := flyCommand.RenamePipeline(teamName, "old-pipeline", "new-pipeline")
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedOutput := fmt.Sprintf(
+				"%s %s %s %s %s %s %s\n",
+				"-t", target,
+				"rename-pipeline",
+				"-o", "old-pipeline",
+				"-n", "new-pipeline",
+			)
+
+			Expect(string(output)).To(Equal(expectedOutput))
+		})
+	})
 })
@@ -8,10 +8,29 @@ import (
 )
 
 type FakeCommand struct {
-	DestroyPipelineStub        func(string) ([]byte, error)
+	ArchivePipelineStub        func(string, string) ([]byte, error)
+	archivePipelineMutex       sync.RWMutex
+	archivePipelineArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	archivePipelineReturns struct {
+		result1 []byte
+		result2 error
+	}
+	archivePipelineReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	CleanupStub        func()
+	cleanupMutex       sync.RWMutex
+	cleanupArgsForCall []struct {
+	}
+	DestroyPipelineStub        func(string, string) ([]byte, error)
 	destroyPipelineMutex       sync.RWMutex
 	destroyPipelineArgsForCall []struct {
 		arg1 string
+		arg2 string
 	}
 	destroyPipelineReturns struct {
 		result1 []byte
@@ -21,10 +40,11 @@ type FakeCommand struct {
 		result1 []byte
 		result2 error
 	}
-	ExposePipelineStub        func(string) ([]byte, error)
+	ExposePipelineStub        func(string, string) ([]byte, error)
 	exposePipelineMutex       sync.RWMutex
 	exposePipelineArgsForCall []struct {
 		arg1 string
+		arg2 string
 	}
 	exposePipelineReturns struct {
 		result1 []byte
@@ -34,10 +54,11 @@ type FakeCommand struct {
 		result1 []byte
 		result2 error
 	}
-	GetPipelineStub        func(string) ([]byte, error)
+	GetPipelineStub        func(string, string) ([]byte, error)
 	getPipelineMutex       sync.RWMutex
 	getPipelineArgsForCall []struct {
 		arg1 string
+		arg2 string
 	}
 	getPipelineReturns struct {
 		result1 []byte
@@ -47,14 +68,41 @@ type FakeCommand struct {
 		result1 []byte
 		result2 error
 	}
-	LoginStub        func(string, string, string, string, bool) ([]byte, error)
+	HidePipelineStub        func(string, string) ([]byte, error)
+	hidePipelineMutex       sync.RWMutex
+	hidePipelineArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	hidePipelineReturns struct {
+		result1 []byte
+		result2 error
+	}
+	hidePipelineReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	JobsStub        func(string, string) ([]fly.Job, error)
+	jobsMutex       sync.RWMutex
+	jobsArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	jobsReturns struct {
+		result1 []fly.Job
+		result2 error
+	}
+	jobsReturnsOnCall map[int]struct {
+		result1 []fly.Job
+		result2 error
+	}
+	LoginStub        func(string, string, fly.Credentials, bool) ([]byte, error)
 	loginMutex       sync.RWMutex
 	loginArgsForCall []struct {
 		arg1 string
 		arg2 string
-		arg3 string
-		arg4 string
-		arg5 bool
+		arg3 fly.Credentials
+		arg4 bool
 	}
 	loginReturns struct {
 		result1 []byte
@@ -64,9 +112,37 @@ type FakeCommand struct {
 		result1 []byte
 		result2 error
 	}
-	PipelinesStub        func() ([]string, error)
+	PausePipelineStub        func(string, string) ([]byte, error)
+	pausePipelineMutex       sync.RWMutex
+	pausePipelineArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	pausePipelineReturns struct {
+		result1 []byte
+		result2 error
+	}
+	pausePipelineReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	PipelineStatesStub        func(string) ([]fly.PipelineState, error)
+	pipelineStatesMutex       sync.RWMutex
+	pipelineStatesArgsForCall []struct {
+		arg1 string
+	}
+	pipelineStatesReturns struct {
+		result1 []fly.PipelineState
+		result2 error
+	}
+	pipelineStatesReturnsOnCall map[int]struct {
+		result1 []fly.PipelineState
+		result2 error
+	}
+	PipelinesStub        func(string) ([]string, error)
 	pipelinesMutex       sync.RWMutex
 	pipelinesArgsForCall []struct {
+		arg1 string
 	}
 	pipelinesReturns struct {
 		result1 []string
@@ -76,13 +152,29 @@ type FakeCommand struct {
 		result1 []string
 		result2 error
 	}
-	SetPipelineStub        func(string, string, []string, map[string]interface{}) ([]byte, error)
+	RenamePipelineStub        func(string, string, string) ([]byte, error)
+	renamePipelineMutex       sync.RWMutex
+	renamePipelineArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	renamePipelineReturns struct {
+		result1 []byte
+		result2 error
+	}
+	renamePipelineReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
+	SetPipelineStub        func(string, string, string, []string, map[string]interface{}) ([]byte, error)
 	setPipelineMutex       sync.RWMutex
 	setPipelineArgsForCall []struct {
 		arg1 string
 		arg2 string
-		arg3 []string
-		arg4 map[string]interface{}
+		arg3 string
+		arg4 []string
+		arg5 map[string]interface{}
 	}
 	setPipelineReturns struct {
 		result1 []byte
@@ -92,10 +184,11 @@ type FakeCommand struct {
 		result1 []byte
 		result2 error
 	}
-	UnpausePipelineStub        func(string) ([]byte, error)
+	UnpausePipelineStub        func(string, string) ([]byte, error)
 	unpausePipelineMutex       sync.RWMutex
 	unpausePipelineArgsForCall []struct {
 		arg1 string
+		arg2 string
 	}
 	unpausePipelineReturns struct {
 		result1 []byte
@@ -109,21 +202,112 @@ type FakeCommand struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCommand) DestroyPipeline(arg1 string) ([]byte, error) {
+func (fake *FakeCommand) ArchivePipeline(arg1 string, arg2 string) ([]byte, error) {
+	fake.archivePipelineMutex.Lock()
+	ret, specificReturn := fake.archivePipelineReturnsOnCall[len(fake.archivePipelineArgsForCall)]
+	fake.archivePipelineArgsForCall = append(fake.archivePipelineArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ArchivePipelineStub
+	fakeReturns := fake.archivePipelineReturns
+	fake.recordInvocation("ArchivePipeline", []interface{}{arg1, arg2})
+	fake.archivePipelineMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCommand) ArchivePipelineCallCount() int {
+	fake.archivePipelineMutex.RLock()
+	defer fake.archivePipelineMutex.RUnlock()
+	return len(fake.archivePipelineArgsForCall)
+}
+
+func (fake *FakeCommand) ArchivePipelineCalls(stub func(string, string) ([]byte, error)) {
+	fake.archivePipelineMutex.Lock()
+	defer fake.archivePipelineMutex.Unlock()
+	fake.ArchivePipelineStub = stub
+}
+
+func (fake *FakeCommand) ArchivePipelineArgsForCall(i int) (string, string) {
+	fake.archivePipelineMutex.RLock()
+	defer fake.archivePipelineMutex.RUnlock()
+	argsForCall := fake.archivePipelineArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeCommand) ArchivePipelineReturns(result1 []byte, result2 error) {
+	fake.archivePipelineMutex.Lock()
+	defer fake.archivePipelineMutex.Unlock()
+	fake.ArchivePipelineStub = nil
+	fake.archivePipelineReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) ArchivePipelineReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.archivePipelineMutex.Lock()
+	defer fake.archivePipelineMutex.Unlock()
+	fake.ArchivePipelineStub = nil
+	if fake.archivePipelineReturnsOnCall == nil {
+		fake.archivePipelineReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.archivePipelineReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) Cleanup() {
+	fake.cleanupMutex.Lock()
+	fake.cleanupArgsForCall = append(fake.cleanupArgsForCall, struct {
+	}{})
+	stub := fake.CleanupStub
+	fake.recordInvocation("Cleanup", []interface{}{})
+	fake.cleanupMutex.Unlock()
+	if stub != nil {
+		fake.CleanupStub()
+	}
+}
+
+func (fake *FakeCommand) CleanupCallCount() int {
+	fake.cleanupMutex.RLock()
+	defer fake.cleanupMutex.RUnlock()
+	return len(fake.cleanupArgsForCall)
+}
+
+func (fake *FakeCommand) CleanupCalls(stub func()) {
+	fake.cleanupMutex.Lock()
+	defer fake.cleanupMutex.Unlock()
+	fake.CleanupStub = stub
+}
+
+func (fake *FakeCommand) DestroyPipeline(arg1 string, arg2 string) ([]byte, error) {
 	fake.destroyPipelineMutex.Lock()
 	ret, specificReturn := fake.destroyPipelineReturnsOnCall[len(fake.destroyPipelineArgsForCall)]
 	fake.destroyPipelineArgsForCall = append(fake.destroyPipelineArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	fake.recordInvocation("DestroyPipeline", []interface{}{arg1})
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.DestroyPipelineStub
+	fakeReturns := fake.destroyPipelineReturns
+	fake.recordInvocation("DestroyPipeline", []interface{}{arg1, arg2})
 	fake.destroyPipelineMutex.Unlock()
-	if fake.DestroyPipelineStub != nil {
-		return fake.DestroyPipelineStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.destroyPipelineReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -133,17 +317,17 @@ func (fake *FakeCommand) DestroyPipelineCallCount() int {
 	return len(fake.destroyPipelineArgsForCall)
 }
 
-func (fake *FakeCommand) DestroyPipelineCalls(stub func(string) ([]byte, error)) {
+func (fake *FakeCommand) DestroyPipelineCalls(stub func(string, string) ([]byte, error)) {
 	fake.destroyPipelineMutex.Lock()
 	defer fake.destroyPipelineMutex.Unlock()
 	fake.DestroyPipelineStub = stub
 }
 
-func (fake *FakeCommand) DestroyPipelineArgsForCall(i int) string {
+func (fake *FakeCommand) DestroyPipelineArgsForCall(i int) (string, string) {
 	fake.destroyPipelineMutex.RLock()
 	defer fake.destroyPipelineMutex.RUnlock()
 	argsForCall := fake.destroyPipelineArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeCommand) DestroyPipelineReturns(result1 []byte, result2 error) {
@@ -172,21 +356,23 @@ func (fake *FakeCommand) DestroyPipelineReturnsOnCall(i int, result1 []byte, res
 	}{result1, result2}
 }
 
-func (fake *FakeCommand) ExposePipeline(arg1 string) ([]byte, error) {
+func (fake *FakeCommand) ExposePipeline(arg1 string, arg2 string) ([]byte, error) {
 	fake.exposePipelineMutex.Lock()
 	ret, specificReturn := fake.exposePipelineReturnsOnCall[len(fake.exposePipelineArgsForCall)]
 	fake.exposePipelineArgsForCall = append(fake.exposePipelineArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	fake.recordInvocation("ExposePipeline", []interface{}{arg1})
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ExposePipelineStub
+	fakeReturns := fake.exposePipelineReturns
+	fake.recordInvocation("ExposePipeline", []interface{}{arg1, arg2})
 	fake.exposePipelineMutex.Unlock()
-	if fake.ExposePipelineStub != nil {
-		return fake.ExposePipelineStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.exposePipelineReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -196,17 +382,17 @@ func (fake *FakeCommand) ExposePipelineCallCount() int {
 	return len(fake.exposePipelineArgsForCall)
 }
 
-func (fake *FakeCommand) ExposePipelineCalls(stub func(string) ([]byte, error)) {
+func (fake *FakeCommand) ExposePipelineCalls(stub func(string, string) ([]byte, error)) {
 	fake.exposePipelineMutex.Lock()
 	defer fake.exposePipelineMutex.Unlock()
 	fake.ExposePipelineStub = stub
 }
 
-func (fake *FakeCommand) ExposePipelineArgsForCall(i int) string {
+func (fake *FakeCommand) ExposePipelineArgsForCall(i int) (string, string) {
 	fake.exposePipelineMutex.RLock()
 	defer fake.exposePipelineMutex.RUnlock()
 	argsForCall := fake.exposePipelineArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeCommand) ExposePipelineReturns(result1 []byte, result2 error) {
@@ -235,21 +421,23 @@ func (fake *FakeCommand) ExposePipelineReturnsOnCall(i int, result1 []byte, resu
 	}{result1, result2}
 }
 
-func (fake *FakeCommand) GetPipeline(arg1 string) ([]byte, error) {
+func (fake *FakeCommand) GetPipeline(arg1 string, arg2 string) ([]byte, error) {
 	fake.getPipelineMutex.Lock()
 	ret, specificReturn := fake.getPipelineReturnsOnCall[len(fake.getPipelineArgsForCall)]
 	fake.getPipelineArgsForCall = append(fake.getPipelineArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	fake.recordInvocation("GetPipeline", []interface{}{arg1})
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetPipelineStub
+	fakeReturns := fake.getPipelineReturns
+	fake.recordInvocation("GetPipeline", []interface{}{arg1, arg2})
 	fake.getPipelineMutex.Unlock()
-	if fake.GetPipelineStub != nil {
-		return fake.GetPipelineStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getPipelineReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -259,17 +447,17 @@ func (fake *FakeCommand) GetPipelineCallCount() int {
 	return len(fake.getPipelineArgsForCall)
 }
 
-func (fake *FakeCommand) GetPipelineCalls(stub func(string) ([]byte, error)) {
+func (fake *FakeCommand) GetPipelineCalls(stub func(string, string) ([]byte, error)) {
 	fake.getPipelineMutex.Lock()
 	defer fake.getPipelineMutex.Unlock()
 	fake.GetPipelineStub = stub
 }
 
-func (fake *FakeCommand) GetPipelineArgsForCall(i int) string {
+func (fake *FakeCommand) GetPipelineArgsForCall(i int) (string, string) {
 	fake.getPipelineMutex.RLock()
 	defer fake.getPipelineMutex.RUnlock()
 	argsForCall := fake.getPipelineArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeCommand) GetPipelineReturns(result1 []byte, result2 error) {
@@ -298,25 +486,155 @@ func (fake *FakeCommand) GetPipelineReturnsOnCall(i int, result1 []byte, result2
 	}{result1, result2}
 }
 
-func (fake *FakeCommand) Login(arg1 string, arg2 string, arg3 string, arg4 string, arg5 bool) ([]byte, error) {
+func (fake *FakeCommand) HidePipeline(arg1 string, arg2 string) ([]byte, error) {
+	fake.hidePipelineMutex.Lock()
+	ret, specificReturn := fake.hidePipelineReturnsOnCall[len(fake.hidePipelineArgsForCall)]
+	fake.hidePipelineArgsForCall = append(fake.hidePipelineArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.HidePipelineStub
+	fakeReturns := fake.hidePipelineReturns
+	fake.recordInvocation("HidePipeline", []interface{}{arg1, arg2})
+	fake.hidePipelineMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCommand) HidePipelineCallCount() int {
+	fake.hidePipelineMutex.RLock()
+	defer fake.hidePipelineMutex.RUnlock()
+	return len(fake.hidePipelineArgsForCall)
+}
+
+func (fake *FakeCommand) HidePipelineCalls(stub func(string, string) ([]byte, error)) {
+	fake.hidePipelineMutex.Lock()
+	defer fake.hidePipelineMutex.Unlock()
+	fake.HidePipelineStub = stub
+}
+
+func (fake *FakeCommand) HidePipelineArgsForCall(i int) (string, string) {
+	fake.hidePipelineMutex.RLock()
+	defer fake.hidePipelineMutex.RUnlock()
+	argsForCall := fake.hidePipelineArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeCommand) HidePipelineReturns(result1 []byte, result2 error) {
+	fake.hidePipelineMutex.Lock()
+	defer fake.hidePipelineMutex.Unlock()
+	fake.HidePipelineStub = nil
+	fake.hidePipelineReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) HidePipelineReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.hidePipelineMutex.Lock()
+	defer fake.hidePipelineMutex.Unlock()
+	fake.HidePipelineStub = nil
+	if fake.hidePipelineReturnsOnCall == nil {
+		fake.hidePipelineReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.hidePipelineReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) Jobs(arg1 string, arg2 string) ([]fly.Job, error) {
+	fake.jobsMutex.Lock()
+	ret, specificReturn := fake.jobsReturnsOnCall[len(fake.jobsArgsForCall)]
+	fake.jobsArgsForCall = append(fake.jobsArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.JobsStub
+	fakeReturns := fake.jobsReturns
+	fake.recordInvocation("Jobs", []interface{}{arg1, arg2})
+	fake.jobsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCommand) JobsCallCount() int {
+	fake.jobsMutex.RLock()
+	defer fake.jobsMutex.RUnlock()
+	return len(fake.jobsArgsForCall)
+}
+
+func (fake *FakeCommand) JobsCalls(stub func(string, string) ([]fly.Job, error)) {
+	fake.jobsMutex.Lock()
+	defer fake.jobsMutex.Unlock()
+	fake.JobsStub = stub
+}
+
+func (fake *FakeCommand) JobsArgsForCall(i int) (string, string) {
+	fake.jobsMutex.RLock()
+	defer fake.jobsMutex.RUnlock()
+	argsForCall := fake.jobsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeCommand) JobsReturns(result1 []fly.Job, result2 error) {
+	fake.jobsMutex.Lock()
+	defer fake.jobsMutex.Unlock()
+	fake.JobsStub = nil
+	fake.jobsReturns = struct {
+		result1 []fly.Job
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) JobsReturnsOnCall(i int, result1 []fly.Job, result2 error) {
+	fake.jobsMutex.Lock()
+	defer fake.jobsMutex.Unlock()
+	fake.JobsStub = nil
+	if fake.jobsReturnsOnCall == nil {
+		fake.jobsReturnsOnCall = make(map[int]struct {
+			result1 []fly.Job
+			result2 error
+		})
+	}
+	fake.jobsReturnsOnCall[i] = struct {
+		result1 []fly.Job
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) Login(arg1 string, arg2 string, arg3 fly.Credentials, arg4 bool) ([]byte, error) {
 	fake.loginMutex.Lock()
 	ret, specificReturn := fake.loginReturnsOnCall[len(fake.loginArgsForCall)]
 	fake.loginArgsForCall = append(fake.loginArgsForCall, struct {
 		arg1 string
 		arg2 string
-		arg3 string
-		arg4 string
-		arg5 bool
-	}{arg1, arg2, arg3, arg4, arg5})
-	fake.recordInvocation("Login", []interface{}{arg1, arg2, arg3, arg4, arg5})
+		arg3 fly.Credentials
+		arg4 bool
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.LoginStub
+	fakeReturns := fake.loginReturns
+	fake.recordInvocation("Login", []interface{}{arg1, arg2, arg3, arg4})
 	fake.loginMutex.Unlock()
-	if fake.LoginStub != nil {
-		return fake.LoginStub(arg1, arg2, arg3, arg4, arg5)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.loginReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -326,17 +644,17 @@ func (fake *FakeCommand) LoginCallCount() int {
 	return len(fake.loginArgsForCall)
 }
 
-func (fake *FakeCommand) LoginCalls(stub func(string, string, string, string, bool) ([]byte, error)) {
+func (fake *FakeCommand) LoginCalls(stub func(string, string, fly.Credentials, bool) ([]byte, error)) {
 	fake.loginMutex.Lock()
 	defer fake.loginMutex.Unlock()
 	fake.LoginStub = stub
 }
 
-func (fake *FakeCommand) LoginArgsForCall(i int) (string, string, string, string, bool) {
+func (fake *FakeCommand) LoginArgsForCall(i int) (string, string, fly.Credentials, bool) {
 	fake.loginMutex.RLock()
 	defer fake.loginMutex.RUnlock()
 	argsForCall := fake.loginArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeCommand) LoginReturns(result1 []byte, result2 error) {
@@ -365,20 +683,151 @@ func (fake *FakeCommand) LoginReturnsOnCall(i int, result1 []byte, result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeCommand) Pipelines() ([]string, error) {
+func (fake *FakeCommand) PausePipeline(arg1 string, arg2 string) ([]byte, error) {
+	fake.pausePipelineMutex.Lock()
+	ret, specificReturn := fake.pausePipelineReturnsOnCall[len(fake.pausePipelineArgsForCall)]
+	fake.pausePipelineArgsForCall = append(fake.pausePipelineArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.PausePipelineStub
+	fakeReturns := fake.pausePipelineReturns
+	fake.recordInvocation("PausePipeline", []interface{}{arg1, arg2})
+	fake.pausePipelineMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCommand) PausePipelineCallCount() int {
+	fake.pausePipelineMutex.RLock()
+	defer fake.pausePipelineMutex.RUnlock()
+	return len(fake.pausePipelineArgsForCall)
+}
+
+func (fake *FakeCommand) PausePipelineCalls(stub func(string, string) ([]byte, error)) {
+	fake.pausePipelineMutex.Lock()
+	defer fake.pausePipelineMutex.Unlock()
+	fake.PausePipelineStub = stub
+}
+
+func (fake *FakeCommand) PausePipelineArgsForCall(i int) (string, string) {
+	fake.pausePipelineMutex.RLock()
+	defer fake.pausePipelineMutex.RUnlock()
+	argsForCall := fake.pausePipelineArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeCommand) PausePipelineReturns(result1 []byte, result2 error) {
+	fake.pausePipelineMutex.Lock()
+	defer fake.pausePipelineMutex.Unlock()
+	fake.PausePipelineStub = nil
+	fake.pausePipelineReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) PausePipelineReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.pausePipelineMutex.Lock()
+	defer fake.pausePipelineMutex.Unlock()
+	fake.PausePipelineStub = nil
+	if fake.pausePipelineReturnsOnCall == nil {
+		fake.pausePipelineReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.pausePipelineReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) PipelineStates(arg1 string) ([]fly.PipelineState, error) {
+	fake.pipelineStatesMutex.Lock()
+	ret, specificReturn := fake.pipelineStatesReturnsOnCall[len(fake.pipelineStatesArgsForCall)]
+	fake.pipelineStatesArgsForCall = append(fake.pipelineStatesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.PipelineStatesStub
+	fakeReturns := fake.pipelineStatesReturns
+	fake.recordInvocation("PipelineStates", []interface{}{arg1})
+	fake.pipelineStatesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCommand) PipelineStatesCallCount() int {
+	fake.pipelineStatesMutex.RLock()
+	defer fake.pipelineStatesMutex.RUnlock()
+	return len(fake.pipelineStatesArgsForCall)
+}
+
+func (fake *FakeCommand) PipelineStatesCalls(stub func(string) ([]fly.PipelineState, error)) {
+	fake.pipelineStatesMutex.Lock()
+	defer fake.pipelineStatesMutex.Unlock()
+	fake.PipelineStatesStub = stub
+}
+
+func (fake *FakeCommand) PipelineStatesArgsForCall(i int) string {
+	fake.pipelineStatesMutex.RLock()
+	defer fake.pipelineStatesMutex.RUnlock()
+	argsForCall := fake.pipelineStatesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCommand) PipelineStatesReturns(result1 []fly.PipelineState, result2 error) {
+	fake.pipelineStatesMutex.Lock()
+	defer fake.pipelineStatesMutex.Unlock()
+	fake.PipelineStatesStub = nil
+	fake.pipelineStatesReturns = struct {
+		result1 []fly.PipelineState
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) PipelineStatesReturnsOnCall(i int, result1 []fly.PipelineState, result2 error) {
+	fake.pipelineStatesMutex.Lock()
+	defer fake.pipelineStatesMutex.Unlock()
+	fake.PipelineStatesStub = nil
+	if fake.pipelineStatesReturnsOnCall == nil {
+		fake.pipelineStatesReturnsOnCall = make(map[int]struct {
+			result1 []fly.PipelineState
+			result2 error
+		})
+	}
+	fake.pipelineStatesReturnsOnCall[i] = struct {
+		result1 []fly.PipelineState
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) Pipelines(arg1 string) ([]string, error) {
 	fake.pipelinesMutex.Lock()
 	ret, specificReturn := fake.pipelinesReturnsOnCall[len(fake.pipelinesArgsForCall)]
 	fake.pipelinesArgsForCall = append(fake.pipelinesArgsForCall, struct {
-	}{})
-	fake.recordInvocation("Pipelines", []interface{}{})
+		arg1 string
+	}{arg1})
+	stub := fake.PipelinesStub
+	fakeReturns := fake.pipelinesReturns
+	fake.recordInvocation("Pipelines", []interface{}{arg1})
 	fake.pipelinesMutex.Unlock()
-	if fake.PipelinesStub != nil {
-		return fake.PipelinesStub()
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.pipelinesReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -388,12 +837,19 @@ func (fake *FakeCommand) PipelinesCallCount() int {
 	return len(fake.pipelinesArgsForCall)
 }
 
-func (fake *FakeCommand) PipelinesCalls(stub func() ([]string, error)) {
+func (fake *FakeCommand) PipelinesCalls(stub func(string) ([]string, error)) {
 	fake.pipelinesMutex.Lock()
 	defer fake.pipelinesMutex.Unlock()
 	fake.PipelinesStub = stub
 }
 
+func (fake *FakeCommand) PipelinesArgsForCall(i int) string {
+	fake.pipelinesMutex.RLock()
+	defer fake.pipelinesMutex.RUnlock()
+	argsForCall := fake.pipelinesArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeCommand) PipelinesReturns(result1 []string, result2 error) {
 	fake.pipelinesMutex.Lock()
 	defer fake.pipelinesMutex.Unlock()
@@ -420,29 +876,97 @@ func (fake *FakeCommand) PipelinesReturnsOnCall(i int, result1 []string, result2
 	}{result1, result2}
 }
 
-func (fake *FakeCommand) SetPipeline(arg1 string, arg2 string, arg3 []string, arg4 map[string]interface{}) ([]byte, error) {
-	var arg3Copy []string
-	if arg3 != nil {
-		arg3Copy = make([]string, len(arg3))
-		copy(arg3Copy, arg3)
+func (fake *FakeCommand) RenamePipeline(arg1 string, arg2 string, arg3 string) ([]byte, error) {
+	fake.renamePipelineMutex.Lock()
+	ret, specificReturn := fake.renamePipelineReturnsOnCall[len(fake.renamePipelineArgsForCall)]
+	fake.renamePipelineArgsForCall = append(fake.renamePipelineArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RenamePipelineStub
+	fakeReturns := fake.renamePipelineReturns
+	fake.recordInvocation("RenamePipeline", []interface{}{arg1, arg2, arg3})
+	fake.renamePipelineMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCommand) RenamePipelineCallCount() int {
+	fake.renamePipelineMutex.RLock()
+	defer fake.renamePipelineMutex.RUnlock()
+	return len(fake.renamePipelineArgsForCall)
+}
+
+func (fake *FakeCommand) RenamePipelineCalls(stub func(string, string, string) ([]byte, error)) {
+	fake.renamePipelineMutex.Lock()
+	defer fake.renamePipelineMutex.Unlock()
+	fake.RenamePipelineStub = stub
+}
+
+func (fake *FakeCommand) RenamePipelineArgsForCall(i int) (string, string, string) {
+	fake.renamePipelineMutex.RLock()
+	defer fake.renamePipelineMutex.RUnlock()
+	argsForCall := fake.renamePipelineArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeCommand) RenamePipelineReturns(result1 []byte, result2 error) {
+	fake.renamePipelineMutex.Lock()
+	defer fake.renamePipelineMutex.Unlock()
+	fake.RenamePipelineStub = nil
+	fake.renamePipelineReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) RenamePipelineReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.renamePipelineMutex.Lock()
+	defer fake.renamePipelineMutex.Unlock()
+	fake.RenamePipelineStub = nil
+	if fake.renamePipelineReturnsOnCall == nil {
+		fake.renamePipelineReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.renamePipelineReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCommand) SetPipeline(arg1 string, arg2 string, arg3 string, arg4 []string, arg5 map[string]interface{}) ([]byte, error) {
+	var arg4Copy []string
+	if arg4 != nil {
+		arg4Copy = make([]string, len(arg4))
+		copy(arg4Copy, arg4)
 	}
 	fake.setPipelineMutex.Lock()
 	ret, specificReturn := fake.setPipelineReturnsOnCall[len(fake.setPipelineArgsForCall)]
 	fake.setPipelineArgsForCall = append(fake.setPipelineArgsForCall, struct {
 		arg1 string
 		arg2 string
-		arg3 []string
-		arg4 map[string]interface{}
-	}{arg1, arg2, arg3Copy, arg4})
-	fake.recordInvocation("SetPipeline", []interface{}{arg1, arg2, arg3Copy, arg4})
+		arg3 string
+		arg4 []string
+		arg5 map[string]interface{}
+	}{arg1, arg2, arg3, arg4Copy, arg5})
+	stub := fake.SetPipelineStub
+	fakeReturns := fake.setPipelineReturns
+	fake.recordInvocation("SetPipeline", []interface{}{arg1, arg2, arg3, arg4Copy, arg5})
 	fake.setPipelineMutex.Unlock()
-	if fake.SetPipelineStub != nil {
-		return fake.SetPipelineStub(arg1, arg2, arg3, arg4)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.setPipelineReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -452,17 +976,17 @@ func (fake *FakeCommand) SetPipelineCallCount() int {
 	return len(fake.setPipelineArgsForCall)
 }
 
-func (fake *FakeCommand) SetPipelineCalls(stub func(string, string, []string, map[string]interface{}) ([]byte, error)) {
+func (fake *FakeCommand) SetPipelineCalls(stub func(string, string, string, []string, map[string]interface{}) ([]byte, error)) {
 	fake.setPipelineMutex.Lock()
 	defer fake.setPipelineMutex.Unlock()
 	fake.SetPipelineStub = stub
 }
 
-func (fake *FakeCommand) SetPipelineArgsForCall(i int) (string, string, []string, map[string]interface{}) {
+func (fake *FakeCommand) SetPipelineArgsForCall(i int) (string, string, string, []string, map[string]interface{}) {
 	fake.setPipelineMutex.RLock()
 	defer fake.setPipelineMutex.RUnlock()
 	argsForCall := fake.setPipelineArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeCommand) SetPipelineReturns(result1 []byte, result2 error) {
@@ -491,21 +1015,23 @@ func (fake *FakeCommand) SetPipelineReturnsOnCall(i int, result1 []byte, result2
 	}{result1, result2}
 }
 
-func (fake *FakeCommand) UnpausePipeline(arg1 string) ([]byte, error) {
+func (fake *FakeCommand) UnpausePipeline(arg1 string, arg2 string) ([]byte, error) {
 	fake.unpausePipelineMutex.Lock()
 	ret, specificReturn := fake.unpausePipelineReturnsOnCall[len(fake.unpausePipelineArgsForCall)]
 	fake.unpausePipelineArgsForCall = append(fake.unpausePipelineArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	fake.recordInvocation("UnpausePipeline", []interface{}{arg1})
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.UnpausePipelineStub
+	fakeReturns := fake.unpausePipelineReturns
+	fake.recordInvocation("UnpausePipeline", []interface{}{arg1, arg2})
 	fake.unpausePipelineMutex.Unlock()
-	if fake.UnpausePipelineStub != nil {
-		return fake.UnpausePipelineStub(arg1)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.unpausePipelineReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -515,17 +1041,17 @@ func (fake *FakeCommand) UnpausePipelineCallCount() int {
 	return len(fake.unpausePipelineArgsForCall)
 }
 
-func (fake *FakeCommand) UnpausePipelineCalls(stub func(string) ([]byte, error)) {
+func (fake *FakeCommand) UnpausePipelineCalls(stub func(string, string) ([]byte, error)) {
 	fake.unpausePipelineMutex.Lock()
 	defer fake.unpausePipelineMutex.Unlock()
 	fake.UnpausePipelineStub = stub
 }
 
-func (fake *FakeCommand) UnpausePipelineArgsForCall(i int) string {
+func (fake *FakeCommand) UnpausePipelineArgsForCall(i int) (string, string) {
 	fake.unpausePipelineMutex.RLock()
 	defer fake.unpausePipelineMutex.RUnlock()
 	argsForCall := fake.unpausePipelineArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeCommand) UnpausePipelineReturns(result1 []byte, result2 error) {
@@ -557,16 +1083,30 @@ func (fake *FakeCommand) UnpausePipelineReturnsOnCall(i int, result1 []byte, res
 func (fake *FakeCommand) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.archivePipelineMutex.RLock()
+	defer fake.archivePipelineMutex.RUnlock()
+	fake.cleanupMutex.RLock()
+	defer fake.cleanupMutex.RUnlock()
 	fake.destroyPipelineMutex.RLock()
 	defer fake.destroyPipelineMutex.RUnlock()
 	fake.exposePipelineMutex.RLock()
 	defer fake.exposePipelineMutex.RUnlock()
 	fake.getPipelineMutex.RLock()
 	defer fake.getPipelineMutex.RUnlock()
+	fake.hidePipelineMutex.RLock()
+	defer fake.hidePipelineMutex.RUnlock()
+	fake.jobsMutex.RLock()
+	defer fake.jobsMutex.RUnlock()
 	fake.loginMutex.RLock()
 	defer fake.loginMutex.RUnlock()
+	fake.pausePipelineMutex.RLock()
+	defer fake.pausePipelineMutex.RUnlock()
+	fake.pipelineStatesMutex.RLock()
+	defer fake.pipelineStatesMutex.RUnlock()
 	fake.pipelinesMutex.RLock()
 	defer fake.pipelinesMutex.RUnlock()
+	fake.renamePipelineMutex.RLock()
+	defer fake.renamePipelineMutex.RUnlock()
 	fake.setPipelineMutex.RLock()
 	defer fake.setPipelineMutex.RUnlock()
 	fake.unpausePipelineMutex.RLock()
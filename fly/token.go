@@ -0,0 +1,109 @@
+package fly
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+)
+
+const (
+	// These are the credentials fly itself uses to request tokens.
+	flyClientID     = "fly"
+	flyClientSecret = "Zmx5"
+	tokenScopes     = "openid profile email federated:id groups"
+)
+
+// tokenPaths are tried in order; the first is served by Concourse 6.1 and
+// later, the second by earlier versions.
+var tokenPaths = []string{"/sky/issuer/token", "/sky/token"}
+
+// requestToken requests a token for the team from the token endpoint of the
+// ATC at atcURL, returning its type and value. Credentials for the password
+// grant are taken from credentials; otherwise the client credentials grant is
+// used. The token is added to secrets, so that it is never logged.
+func requestToken(
+	logger logger.Logger,
+	secrets *redact.Secrets,
+	httpClient *http.Client,
+	atcURL string,
+	teamName string,
+	credentials Credentials,
+) (string, string, error) {
+	clientID := flyClientID
+	clientSecret := flyClientSecret
+
+	form := url.Values{
+		"scope": {tokenScopes},
+	}
+
+	if credentials.ClientID != "" {
+		clientID = credentials.ClientID
+		clientSecret = credentials.ClientSecret
+		form.Set("grant_type", "client_credentials")
+	} else {
+		form.Set("grant_type", "password")
+		form.Set("username", credentials.Username)
+		form.Set("password", credentials.Password)
+	}
+
+	for _, path := range tokenPaths {
+		req, err := http.NewRequest(
+			"POST",
+			strings.TrimRight(atcURL, "/")+path,
+			strings.NewReader(form.Encode()),
+		)
+		if err != nil {
+			return "", "", err
+		}
+		req.SetBasicAuth(clientID, clientSecret)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		logger.Debugf("Requesting token from: %s\n", path)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", "", err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", "", err
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return "", "", fmt.Errorf("failed to log in: %s returned %d - %s", path, resp.StatusCode, body)
+		}
+
+		var t struct {
+			TokenType   string `json:"token_type"`
+			AccessToken string `json:"access_token"`
+			IDToken     string `json:"id_token"`
+		}
+		err = json.Unmarshal(body, &t)
+		if err != nil {
+			return "", "", err
+		}
+
+		// Concourse 7 and later authorize API requests with the ID token.
+		token := t.AccessToken
+		if t.IDToken != "" {
+			token = t.IDToken
+		}
+
+		secrets.Add(token, fmt.Sprintf("***REDACTED-TOKEN-TEAM-%s***", teamName))
+
+		return t.TokenType, token, nil
+	}
+
+	return "", "", fmt.Errorf("failed to log in: no token endpoint found")
+}
@@ -5,31 +5,48 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
 	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/pool"
+	"github.com/concourse/concourse-pipeline-resource/store"
 )
 
 const (
 	apiPrefix = "/api/v1"
 )
 
+// Permissions of the configs, and the directories containing them, written
+// to the destination.
+const (
+	filePerm os.FileMode = 0644
+	dirPerm  os.FileMode = 0755
+)
+
 type Command struct {
 	logger      logger.Logger
 	flyCommand  fly.Command
+	archive     store.Store
 	downloadDir string
 }
 
+// NewCommand returns an in command. If archive is not nil, the configs of
+// the requested version which are no longer current are fetched from it.
 func NewCommand(
 	logger logger.Logger,
 	flyCommand fly.Command,
+	archive store.Store,
 	downloadDir string,
 ) *Command {
 	return &Command{
 		logger:      logger,
 		flyCommand:  flyCommand,
+		archive:     archive,
 		downloadDir: downloadDir,
 	}
 }
@@ -37,6 +54,21 @@ func NewCommand(
 func (c *Command) Run(input concourse.InRequest) (concourse.InResponse, error) {
 	c.logger.Debugf("Received input: %+v\n", input)
 
+	if input.Version.IsLegacy() {
+		c.logger.Debugf("Received legacy version not qualified by team name\n")
+	}
+
+	if input.Params.SkipDownload {
+		c.logger.Debugf("Skipping download\n")
+
+		return concourse.InResponse{
+			Version:  input.Version,
+			Metadata: []concourse.Metadata{},
+		}, nil
+	}
+
+	selected := newSelection(input.Params)
+
 	insecure := false
 	if input.Source.Insecure != "" {
 		var err error
@@ -52,61 +84,228 @@ func (c *Command) Run(input concourse.InRequest) (concourse.InResponse, error) {
 		teams[team.Name] = team
 	}
 
-	for teamName, team := range teams {
+	teamNames := make([]string, 0, len(teams))
+	for teamName := range teams {
+		if selected.team(teamName) {
+			teamNames = append(teamNames, teamName)
+		}
+	}
+	sort.Strings(teamNames)
+
+	teamRefs := make([][]pipelineRef, len(teamNames))
+
+	err := pool.Run(input.Source.MaxConcurrency, len(teamNames), func(i int) error {
+		teamName := teamNames[i]
+		team := teams[teamName]
+
 		c.logger.Debugf("Performing login\n")
 		_, err := c.flyCommand.Login(
 			input.Source.Target,
 			teamName,
-			team.Username,
-			team.Password,
+			fly.TeamCredentials(team),
 			insecure,
 		)
 		if err != nil {
-			return concourse.InResponse{}, err
+			return err
 		}
 
 		c.logger.Debugf("Login successful\n")
 
-		pipelines, err := c.flyCommand.Pipelines()
+		pipelines, err := c.flyCommand.Pipelines(teamName)
 		if err != nil {
-			return concourse.InResponse{}, err
+			return err
 		}
 		c.logger.Debugf("Found pipelines (%s): %+v\n", teamName, pipelines)
 
+		pipelines, err = team.Pipelines.Filter(pipelines)
+		if err != nil {
+			return err
+		}
+
+		pipelines, err = selected.pipelines.Filter(pipelines)
+		if err != nil {
+			return err
+		}
+		c.logger.Debugf("Filtered pipelines (%s): %+v\n", teamName, pipelines)
+
 		for _, pipelineName := range pipelines {
-			outContents, err := c.flyCommand.GetPipeline(pipelineName)
+			teamRefs[i] = append(teamRefs[i], pipelineRef{
+				teamName:     teamName,
+				pipelineName: pipelineName,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return concourse.InResponse{}, err
+	}
+
+	var refs []pipelineRef
+	for _, r := range teamRefs {
+		refs = append(refs, r...)
+	}
+
+	configs := make([][]byte, len(refs))
+
+	err = pool.Run(input.Source.MaxConcurrency, len(refs), func(i int) error {
+		start := time.Now()
+
+		var err error
+		configs[i], err = c.flyCommand.GetPipeline(refs[i].teamName, refs[i].pipelineName)
+		if err != nil {
+			return err
+		}
+
+		c.logger.With(
+			logger.Team(refs[i].teamName),
+			logger.Pipeline(refs[i].pipelineName),
+			logger.Operation("get-pipeline"),
+			logger.Duration(time.Since(start)),
+		).Infof("Fetched pipeline config\n")
+
+		return nil
+	})
+	if err != nil {
+		return concourse.InResponse{}, err
+	}
+
+	pipelines, err := c.resolveVersion(input.Version, selected, refs, configs)
+	if err != nil {
+		return concourse.InResponse{}, err
+	}
+
+	var statuses []*pipelineStatus
+	if input.Params.ExportStatus {
+		statuses, err = c.getStatuses(input.Source.MaxConcurrency, refs, pipelines)
+		if err != nil {
+			return concourse.InResponse{}, err
+		}
+	}
+
+	filenames, err := filenameTemplate(input.Params)
+	if err != nil {
+		return concourse.InResponse{}, err
+	}
+
+	ext := formatExtension(input.Params.Format)
+
+	fetched := make([]string, 0, len(pipelines))
+	restored := []string{}
+	manifest := make([]manifestEntry, 0, len(pipelines))
+
+	// The key of the pipeline written to each file, so that pipelines
+	// written to the same file are reported rather than overwritten.
+	written := map[string]string{manifestFilename: ""}
+
+	claim := func(filename string, key string) error {
+		if other, found := written[filename]; found {
+			if other == "" {
+				return fmt.Errorf("pipeline '%s' can not be written to '%s', which is the manifest", key, filename)
+			}
+			return fmt.Errorf("pipelines '%s' and '%s' are both written to '%s'", other, key, filename)
+		}
+		written[filename] = key
+
+		return nil
+	}
+
+	for i, p := range pipelines {
+		key := concourse.VersionKey(p.teamName, p.pipelineName)
+
+		filename, err := pipelineFilename(filenames, ext, p.teamName, p.pipelineName)
+		if err != nil {
+			return concourse.InResponse{}, err
+		}
+
+		err = claim(filename, key)
+		if err != nil {
+			return concourse.InResponse{}, err
+		}
+
+		contents, err := formatConfig(p.config, input.Params.Format)
+		if err != nil {
+			return concourse.InResponse{}, fmt.Errorf("failed to format pipeline '%s': %v", key, err)
+		}
+
+		pipelineContentsFilepath := filepath.Join(c.downloadDir, filepath.FromSlash(filename))
+		c.logger.Debugf(
+			"Writing pipeline contents to: %s\n",
+			pipelineContentsFilepath,
+		)
+
+		err = os.MkdirAll(filepath.Dir(pipelineContentsFilepath), dirPerm)
+		if err != nil {
+			return concourse.InResponse{}, err
+		}
+
+		// Untested as it is too hard to force ioutil.WriteFile to error
+		err = ioutil.WriteFile(pipelineContentsFilepath, contents, filePerm)
+		if err != nil {
+			return concourse.InResponse{}, err
+		}
+
+		ref, err := concourse.ParsePipelineRef(p.pipelineName)
+		if err != nil {
+			// Untested as the filename would have failed to parse it
+			return concourse.InResponse{}, err
+		}
+
+		entry := manifestEntry{
+			Team:         p.teamName,
+			Name:         ref.Name,
+			InstanceVars: ref.InstanceVars,
+			Version:      store.Version(p.config),
+			File:         filename,
+		}
+
+		if statuses != nil && statuses[i] != nil {
+			entry.StatusFile = statusFilename(filename)
+
+			err = claim(entry.StatusFile, key)
 			if err != nil {
 				return concourse.InResponse{}, err
 			}
-			pipelineContentsFilepath := filepath.Join(
-				c.downloadDir,
-				fmt.Sprintf(
-					"%s-%s.yml",
-					teamName,
-					pipelineName,
-				),
-			)
-			c.logger.Debugf(
-				"Writing pipeline contents to: %s\n",
-				pipelineContentsFilepath,
-			)
-			err = ioutil.WriteFile(pipelineContentsFilepath, outContents, os.ModePerm)
-			// Untested as it is too hard to force ioutil.WriteFile to error
+
+			c.logger.Debugf("Writing pipeline status to: %s\n", entry.StatusFile)
+			err = c.writeStatus(entry.StatusFile, *statuses[i])
 			if err != nil {
 				return concourse.InResponse{}, err
 			}
 		}
+
+		manifest = append(manifest, entry)
+
+		fetched = append(fetched, key)
+		if p.restored {
+			restored = append(restored, key)
+		}
+	}
+
+	err = c.writeManifest(manifest)
+	if err != nil {
+		return concourse.InResponse{}, err
+	}
+
+	metadata := []concourse.Metadata{
+		{Name: "teams", Value: strconv.Itoa(len(teamNames))},
+		{Name: "pipelines", Value: strconv.Itoa(len(pipelines))},
+		{Name: "fetched", Value: strings.Join(fetched, ", ")},
+	}
+
+	if len(restored) > 0 {
+		metadata = append(metadata, concourse.Metadata{Name: "restored", Value: strings.Join(restored, ", ")})
 	}
 
 	response := concourse.InResponse{
 		Version:  input.Version,
-		Metadata: []concourse.Metadata{},
+		Metadata: metadata,
 	}
 
 	return response, nil
 }
 
-type pipelineWithContent struct {
-	name     string
-	contents []byte
+type pipelineRef struct {
+	teamName     string
+	pipelineName string
 }
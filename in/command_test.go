@@ -1,17 +1,23 @@
 package in_test
 
 import (
+	"crypto/md5"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/fly/flyfakes"
 	"github.com/concourse/concourse-pipeline-resource/in"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/store"
+	"github.com/concourse/concourse-pipeline-resource/store/storefakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/robdimsdale/sanitizer"
 )
 
@@ -28,6 +34,9 @@ var _ = Describe("In", func() {
 		command   *in.Command
 
 		fakeFlyCommand *flyfakes.FakeCommand
+		archive        store.Store
+
+		logOutput *gbytes.Buffer
 
 		pipelines        []string
 		pipelineVersions []string
@@ -55,7 +64,6 @@ var _ = Describe("In", func() {
 
 		pipelinesErr = nil
 		pipelines = []string{"pipeline-1", "pipeline-2"}
-		pipelineVersions = []string{"1234", "2345"}
 		pipelineContents = make([]string, 2)
 
 		pipelineContents[0] = `---
@@ -66,17 +74,25 @@ pipeline1: foo
 pipeline2: foo
 `
 
+		pipelineVersions = []string{
+			fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
+			fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[1]))),
+		}
+
+		archive = nil
+
 		inRequest = concourse.InRequest{
 			Source: concourse.Source{
 				Target: target,
 				Teams:  teams,
 			},
 			Version: concourse.Version{
-				pipelines[0]: pipelineVersions[0],
+				"main/" + pipelines[0]: pipelineVersions[0],
+				"main/" + pipelines[1]: pipelineVersions[1],
 			},
 		}
 
-		fakeFlyCommand.GetPipelineStub = func(name string) ([]byte, error) {
+		fakeFlyCommand.GetPipelineStub = func(teamName string, name string) ([]byte, error) {
 			ginkgoLogger.Debugf("GetPipelineStub for: %s\n", name)
 
 			switch name {
@@ -95,11 +111,12 @@ pipeline2: foo
 		fakeFlyCommand.PipelinesReturns(pipelines, pipelinesErr)
 
 		sanitized := concourse.SanitizedSource(inRequest.Source)
-		sanitizer := sanitizer.NewSanitizer(sanitized, GinkgoWriter)
+		logOutput = gbytes.NewBuffer()
+		sanitizer := sanitizer.NewSanitizer(sanitized, io.MultiWriter(GinkgoWriter, logOutput))
 
 		ginkgoLogger = logger.NewLogger(sanitizer)
 
-		command = in.NewCommand(ginkgoLogger, fakeFlyCommand, downloadDir)
+		command = in.NewCommand(ginkgoLogger, fakeFlyCommand, archive, downloadDir)
 	})
 
 	AfterEach(func() {
@@ -115,8 +132,10 @@ pipeline2: foo
 		files, err := ioutil.ReadDir(downloadDir)
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(files).To(HaveLen(len(pipelines)))
+		// The configs are followed by the manifest.
+		Expect(files).To(HaveLen(len(pipelines) + 1))
 		Expect(files[0].Name()).To(MatchRegexp("%s.yml", pipelines[0]))
+		Expect(files[0].Mode()).To(Equal(os.FileMode(0644)))
 
 		contents, err := ioutil.ReadFile(filepath.Join(downloadDir, files[0].Name()))
 		Expect(err).NotTo(HaveOccurred())
@@ -129,12 +148,415 @@ pipeline2: foo
 		Expect(string(contents)).To(Equal(pipelineContents[1]))
 	})
 
+	It("writes a manifest of the pipelines", func() {
+		_, err := command.Run(inRequest)
+		Expect(err).NotTo(HaveOccurred())
+
+		contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "pipelines.json"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contents).To(MatchJSON(fmt.Sprintf(`[
+			{"team": "main", "name": "pipeline-1", "version": %q, "file": "main-pipeline-1.yml"},
+			{"team": "main", "name": "pipeline-2", "version": %q, "file": "main-pipeline-2.yml"}
+		]`, pipelineVersions[0], pipelineVersions[1])))
+	})
+
+	Context("when the layout is team-dirs", func() {
+		BeforeEach(func() {
+			inRequest.Params.Layout = concourse.InLayoutTeamDirs
+		})
+
+		It("writes the configs of each team to its own directory", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := os.Stat(filepath.Join(downloadDir, "main"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "main", "pipeline-1.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(pipelineContents[0]))
+		})
+	})
+
+	Context("when a filename template is provided", func() {
+		BeforeEach(func() {
+			inRequest.Params.Filename = "configs/{{.Name}}-{{.Team}}.{{.Ext}}"
+		})
+
+		It("writes each config to the path it gives", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "configs", "pipeline-1-main.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(pipelineContents[0]))
+		})
+
+		Context("when it gives the same path for two pipelines", func() {
+			BeforeEach(func() {
+				inRequest.Params.Filename = "{{.Team}}.{{.Ext}}"
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).To(MatchError("pipelines 'main/pipeline-1' and 'main/pipeline-2' are both written to 'main.yml'"))
+			})
+		})
+
+		Context("when it gives the path of the manifest", func() {
+			BeforeEach(func() {
+				inRequest.Params.Filename = "pipelines.json"
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).To(MatchError("pipeline 'main/pipeline-1' can not be written to 'pipelines.json', which is the manifest"))
+			})
+		})
+
+		Context("when it gives a path outside the destination", func() {
+			BeforeEach(func() {
+				inRequest.Params.Filename = "../{{.Name}}.{{.Ext}}"
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).To(MatchError("filename '../pipeline-1.yml' of pipeline 'main/pipeline-1' is not a file within the destination"))
+			})
+		})
+	})
+
+	Context("when the format is normalized-yaml", func() {
+		BeforeEach(func() {
+			pipelineContents[0] = "resources: []\njobs:\n- plan: []\n  name: some-job\n"
+			pipelineVersions[0] = fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0])))
+			inRequest.Version["main/"+pipelines[0]] = pipelineVersions[0]
+			inRequest.Params.Format = concourse.InFormatNormalizedYAML
+		})
+
+		It("writes the configs with their keys sorted", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "main-pipeline-1.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("jobs:\n- name: some-job\n  plan: []\nresources: []\n"))
+		})
+	})
+
+	Context("when the format is json", func() {
+		BeforeEach(func() {
+			inRequest.Params.Format = concourse.InFormatJSON
+		})
+
+		It("writes the configs as JSON", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "main-pipeline-1.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{"pipeline1": "foo"}`))
+
+			manifest, err := ioutil.ReadFile(filepath.Join(downloadDir, "pipelines.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(manifest)).To(ContainSubstring(`"version": "%s"`, pipelineVersions[0]))
+		})
+	})
+
+	Context("when max concurrency is set", func() {
+		BeforeEach(func() {
+			inRequest.Source.MaxConcurrency = 2
+		})
+
+		It("downloads all pipeline configs to the target directory", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			for i, p := range pipelines {
+				contents, err := ioutil.ReadFile(filepath.Join(downloadDir, fmt.Sprintf("main-%s.yml", p)))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(pipelineContents[i]))
+			}
+		})
+	})
+
+	Context("when the team filters pipelines", func() {
+		BeforeEach(func() {
+			inRequest.Source.Teams[0].Pipelines = concourse.PipelineFilter{
+				Exclude: []string{pipelines[1]},
+			}
+			delete(inRequest.Version, "main/"+pipelines[1])
+		})
+
+		It("only downloads matching pipelines", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			files, err := ioutil.ReadDir(downloadDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(files).To(HaveLen(2))
+			Expect(files[0].Name()).To(MatchRegexp("%s.yml", pipelines[0]))
+
+			Expect(fakeFlyCommand.GetPipelineCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when teams are selected", func() {
+		BeforeEach(func() {
+			inRequest.Source.Teams = append(inRequest.Source.Teams, concourse.Team{
+				Name:     "other",
+				Username: "other user",
+				Password: "other password",
+			})
+			inRequest.Params.Teams = []string{"main"}
+		})
+
+		It("only logs in to and downloads the pipelines of those teams", func() {
+			response, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(1))
+			_, teamName, _, _ := fakeFlyCommand.LoginArgsForCall(0)
+			Expect(teamName).To(Equal("main"))
+
+			Expect(response.Metadata).To(ContainElement(concourse.Metadata{Name: "teams", Value: "1"}))
+		})
+
+		Context("when the version has pipelines of other teams", func() {
+			BeforeEach(func() {
+				inRequest.Version["other/"+pipelines[0]] = pipelineVersions[0]
+			})
+
+			It("skips them", func() {
+				response, err := command.Run(inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response.Metadata).To(ContainElement(concourse.Metadata{Name: "fetched", Value: "main/pipeline-1, main/pipeline-2"}))
+				Expect(response.Version).To(Equal(inRequest.Version))
+			})
+		})
+	})
+
+	Context("when pipelines are selected", func() {
+		BeforeEach(func() {
+			inRequest.Params.Pipelines = []string{"*-1"}
+		})
+
+		It("only downloads the matching pipelines of the version", func() {
+			response, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			files, err := ioutil.ReadDir(downloadDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(files).To(HaveLen(2))
+			Expect(files[0].Name()).To(Equal("main-pipeline-1.yml"))
+
+			Expect(fakeFlyCommand.GetPipelineCallCount()).To(Equal(1))
+			Expect(response.Version).To(Equal(inRequest.Version))
+		})
+	})
+
+	Context("when skip_download is true", func() {
+		BeforeEach(func() {
+			inRequest.Params.SkipDownload = true
+		})
+
+		It("returns the provided version without downloading any pipelines", func() {
+			response, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Version).To(Equal(inRequest.Version))
+			Expect(response.Metadata).To(BeEmpty())
+
+			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(0))
+			Expect(fakeFlyCommand.GetPipelineCallCount()).To(Equal(0))
+
+			files, err := ioutil.ReadDir(downloadDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(BeEmpty())
+		})
+	})
+
+	Context("when export_status is true", func() {
+		BeforeEach(func() {
+			inRequest.Params.ExportStatus = true
+
+			fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+				{Name: pipelines[0], LastUpdated: 1600000000},
+				{Name: pipelines[1], Paused: true, LastUpdated: 1600000000},
+				{Name: "archived-pipeline", Archived: true},
+			}, nil)
+
+			fakeFlyCommand.JobsReturns([]fly.Job{
+				{
+					Name:          "build",
+					FinishedBuild: &fly.Build{ID: 1, Name: "3", Status: "succeeded", StartTime: 1600000100, EndTime: 1600000200},
+					NextBuild:     &fly.Build{ID: 2, Name: "4", Status: "pending"},
+				},
+			}, nil)
+		})
+
+		It("writes the status of each pipeline alongside its config", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "main-pipeline-1.status.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(contents).To(MatchJSON(`{
+				"team": "main",
+				"name": "pipeline-1",
+				"paused": false,
+				"public": false,
+				"archived": false,
+				"last_updated": "2020-09-13T12:26:40Z",
+				"jobs": [
+					{
+						"name": "build",
+						"paused": false,
+						"finished_build": {"name": "3", "status": "succeeded", "start_time": "2020-09-13T12:28:20Z", "end_time": "2020-09-13T12:30:00Z"},
+						"next_build": {"name": "4", "status": "pending"}
+					}
+				]
+			}`))
+
+			contents, err = ioutil.ReadFile(filepath.Join(downloadDir, "main-pipeline-2.status.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring(`"paused": true`))
+
+			Expect(fakeFlyCommand.JobsCallCount()).To(Equal(2))
+		})
+
+		It("lists the state of the pipelines of each team once", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.PipelineStatesCallCount()).To(Equal(1))
+			Expect(fakeFlyCommand.PipelineStatesArgsForCall(0)).To(Equal("main"))
+		})
+
+		It("lists the status files in the manifest", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "pipelines.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(contents).To(MatchJSON(fmt.Sprintf(`[
+				{"team": "main", "name": "pipeline-1", "version": %q, "file": "main-pipeline-1.yml", "status_file": "main-pipeline-1.status.json"},
+				{"team": "main", "name": "pipeline-2", "version": %q, "file": "main-pipeline-2.yml", "status_file": "main-pipeline-2.status.json"}
+			]`, pipelineVersions[0], pipelineVersions[1])))
+		})
+
+		Context("when a pipeline of the version no longer exists", func() {
+			BeforeEach(func() {
+				oldContents := "pipeline3: old\n"
+				oldVersion := fmt.Sprintf("%x", md5.Sum([]byte(oldContents)))
+				inRequest.Version["main/pipeline-3"] = oldVersion
+
+				fakeArchive := &storefakes.FakeStore{}
+				fakeArchive.GetReturns([]byte(oldContents), nil)
+				archive = fakeArchive
+			})
+
+			It("writes no status for it", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = os.Stat(filepath.Join(downloadDir, "main-pipeline-3.yml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = os.Stat(filepath.Join(downloadDir, "main-pipeline-3.status.json"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+
+				Expect(fakeFlyCommand.JobsCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when getting the status returns an error", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.JobsReturns(nil, fmt.Errorf("jobs failed"))
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).To(MatchError("jobs failed"))
+			})
+		})
+
+		Context("when listing the state of the pipelines returns an error", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.PipelineStatesReturns(nil, fmt.Errorf("pipelines failed"))
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).To(MatchError("pipelines failed"))
+			})
+		})
+
+		Context("when the state of a pipeline is not listed", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+					{Name: pipelines[0]},
+				}, nil)
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).To(MatchError("pipeline 'pipeline-2' (team 'main') not found"))
+			})
+		})
+	})
+
+	Context("when pipelines are instanced", func() {
+		BeforeEach(func() {
+			pipelines = []string{"instanced/branch:main", `instanced/branch:"feature/x"`}
+			inRequest.Version = concourse.Version{
+				"main/" + pipelines[0]: pipelineVersions[0],
+				"main/" + pipelines[1]: pipelineVersions[0],
+			}
+
+			fakeFlyCommand.GetPipelineReturns([]byte(pipelineContents[0]), nil)
+		})
+
+		It("writes the config of each instance to a file named by its instance vars", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, filename := range []string{"main-instanced@branch:main.yml", `main-instanced@branch:"feature%2Fx".yml`} {
+				contents, err := ioutil.ReadFile(filepath.Join(downloadDir, filename))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(pipelineContents[0]))
+			}
+		})
+
+		Context("when the team filters pipelines", func() {
+			BeforeEach(func() {
+				inRequest.Source.Teams[0].Pipelines = concourse.PipelineFilter{
+					Include: []string{"instanced"},
+				}
+			})
+
+			It("matches every instance by its name", func() {
+				_, err := command.Run(inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.GetPipelineCallCount()).To(Equal(2))
+			})
+		})
+	})
+
 	It("returns provided version", func() {
 		response, err := command.Run(inRequest)
 
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(response.Version[pipelines[0]]).To(Equal(pipelineVersions[0]))
+		Expect(response.Version).To(Equal(inRequest.Version))
 	})
 
 	It("returns metadata", func() {
@@ -142,7 +564,142 @@ pipeline2: foo
 
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(response.Metadata).NotTo(BeNil())
+		Expect(response.Metadata).To(Equal([]concourse.Metadata{
+			{Name: "teams", Value: "1"},
+			{Name: "pipelines", Value: "2"},
+			{Name: "fetched", Value: "main/pipeline-1, main/pipeline-2"},
+		}))
+	})
+
+	Context("when a current pipeline is not part of the requested version", func() {
+		BeforeEach(func() {
+			delete(inRequest.Version, "main/"+pipelines[1])
+			archive = &storefakes.FakeStore{}
+		})
+
+		It("does not download it", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			files, err := ioutil.ReadDir(downloadDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(files).To(HaveLen(2))
+			Expect(files[0].Name()).To(Equal("main-pipeline-1.yml"))
+		})
+	})
+
+	Context("when a legacy version keyed only by pipeline name is requested", func() {
+		BeforeEach(func() {
+			inRequest.Version = concourse.Version{
+				pipelines[0]: pipelineVersions[0],
+			}
+			archive = &storefakes.FakeStore{}
+		})
+
+		It("downloads the pipelines of the version", func() {
+			response, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Metadata).To(ContainElement(concourse.Metadata{Name: "fetched", Value: "main/pipeline-1"}))
+		})
+	})
+
+	Context("when the requested version of a pipeline is not current", func() {
+		var (
+			oldContents string
+			oldVersion  string
+		)
+
+		BeforeEach(func() {
+			oldContents = "pipeline2: old\n"
+			oldVersion = fmt.Sprintf("%x", md5.Sum([]byte(oldContents)))
+
+			inRequest.Version["main/"+pipelines[1]] = oldVersion
+		})
+
+		It("downloads the current config and logs a warning", func() {
+			_, err := command.Run(inRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "main-pipeline-2.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(pipelineContents[1]))
+
+			Expect(logOutput).To(gbytes.Say(fmt.Sprintf(
+				"Pipeline 'main/pipeline-2' is at version %s rather than the requested version %s, and no archive is configured to fetch it from",
+				pipelineVersions[1],
+				oldVersion,
+			)))
+		})
+
+		Context("when the pipeline no longer exists", func() {
+			BeforeEach(func() {
+				pipelines = pipelines[:1]
+			})
+
+			It("downloads the current pipelines and logs a warning", func() {
+				response, err := command.Run(inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response.Metadata).To(ContainElement(concourse.Metadata{Name: "fetched", Value: "main/pipeline-1"}))
+
+				Expect(logOutput).To(gbytes.Say("Pipeline 'main/pipeline-2' no longer exists rather than the requested version"))
+			})
+		})
+
+		Context("when an archive is configured", func() {
+			var fakeArchive *storefakes.FakeStore
+
+			BeforeEach(func() {
+				fakeArchive = &storefakes.FakeStore{}
+				fakeArchive.GetReturns([]byte(oldContents), nil)
+				archive = fakeArchive
+			})
+
+			It("fetches the requested version from the archive", func() {
+				response, err := command.Run(inRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeArchive.GetCallCount()).To(Equal(1))
+				team, name, version := fakeArchive.GetArgsForCall(0)
+				Expect(team).To(Equal("main"))
+				Expect(name).To(Equal(pipelines[1]))
+				Expect(version).To(Equal(oldVersion))
+
+				contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "main-pipeline-2.yml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(oldContents))
+
+				Expect(response.Metadata).To(ContainElement(concourse.Metadata{Name: "restored", Value: "main/pipeline-2"}))
+			})
+
+			Context("when the pipeline no longer exists", func() {
+				BeforeEach(func() {
+					pipelines = pipelines[:1]
+				})
+
+				It("fetches the requested version from the archive", func() {
+					_, err := command.Run(inRequest)
+					Expect(err).NotTo(HaveOccurred())
+
+					contents, err := ioutil.ReadFile(filepath.Join(downloadDir, "main-pipeline-2.yml"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(contents)).To(Equal(oldContents))
+				})
+			})
+
+			Context("when the version is not in the archive", func() {
+				BeforeEach(func() {
+					fakeArchive.GetReturns(nil, store.ErrNotFound)
+				})
+
+				It("returns an error", func() {
+					_, err := command.Run(inRequest)
+					Expect(err).To(MatchError(ContainSubstring("which was not found in the archive")))
+				})
+			})
+		})
 	})
 
 	Context("when insecure parses as true", func() {
@@ -155,7 +712,7 @@ pipeline2: foo
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(1))
-			_, _, _, _, insecure := fakeFlyCommand.LoginArgsForCall(0)
+			_, _, _, insecure := fakeFlyCommand.LoginArgsForCall(0)
 
 			Expect(insecure).To(BeTrue())
 		})
@@ -0,0 +1,48 @@
+package in
+
+import (
+	"encoding/json"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"gopkg.in/yaml.v2"
+)
+
+// formatConfig returns a config, as returned by Concourse, in format.
+func formatConfig(config []byte, format string) ([]byte, error) {
+	switch format {
+	case concourse.InFormatNormalizedYAML:
+		var value interface{}
+		err := yaml.Unmarshal(config, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		// Maps are marshalled with their keys sorted.
+		return yaml.Marshal(value)
+	case concourse.InFormatJSON:
+		var value interface{}
+		err := yaml.Unmarshal(config, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		formatted, err := json.MarshalIndent(concourse.JSONCompatible(value), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		return append(formatted, '\n'), nil
+	default:
+		return config, nil
+	}
+}
+
+// formatExtension returns the extension of the files configs are written to
+// in format.
+func formatExtension(format string) string {
+	if format == concourse.InFormatJSON {
+		return "json"
+	}
+
+	return "yml"
+}
@@ -0,0 +1,90 @@
+package in
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+const (
+	flatFilename     = "{{.Team}}-{{.Name}}{{if .Instance}}@{{.Instance}}{{end}}.{{.Ext}}"
+	teamDirsFilename = "{{.Team}}/{{.Name}}{{if .Instance}}@{{.Instance}}{{end}}.{{.Ext}}"
+)
+
+// FilenameData is available to the filename template, e.g.
+// `{{.Team}}/{{.Name}}.{{.Ext}}`. Slashes, which can not be part of a
+// filename, and percent signs are escaped as `%2F` and `%25`.
+type FilenameData struct {
+	// Team is the name of the pipeline's team.
+	Team string
+
+	// Name is the name of the pipeline.
+	Name string
+
+	// Instance is the instance vars of an instanced pipeline, sorted by
+	// key, e.g. `branch:main,pr:1`. It is empty for other pipelines.
+	Instance string
+
+	// Ext is the extension of the format, `yml` or `json`, without a dot.
+	Ext string
+}
+
+// filenameEscaper escapes the slashes in names and instance vars, which can
+// not be part of a filename, and the escape character itself.
+var filenameEscaper = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// filenameTemplate returns the template of the paths configs are written
+// to: the filename param if provided, otherwise that of the layout.
+func filenameTemplate(params concourse.InParams) (*template.Template, error) {
+	text := params.Filename
+	if text == "" {
+		text = flatFilename
+		if params.Layout == concourse.InLayoutTeamDirs {
+			text = teamDirsFilename
+		}
+	}
+
+	return template.New("filename").Option("missingkey=error").Parse(text)
+}
+
+// pipelineFilename returns the slash-separated path, relative to the
+// destination, of the file the config of a pipeline is written to.
+func pipelineFilename(t *template.Template, ext string, teamName string, pipelineName string) (string, error) {
+	ref, err := concourse.ParsePipelineRef(pipelineName)
+	if err != nil {
+		return "", err
+	}
+
+	var pairs []string
+	for _, v := range concourse.FlattenInstanceVars(ref.InstanceVars) {
+		pairs = append(pairs, v.Key+":"+filenameEscaper.Replace(v.Value))
+	}
+
+	data := FilenameData{
+		Team:     filenameEscaper.Replace(teamName),
+		Name:     filenameEscaper.Replace(ref.Name),
+		Instance: strings.Join(pairs, ","),
+		Ext:      ext,
+	}
+
+	var b bytes.Buffer
+	err = t.Execute(&b, data)
+	if err != nil {
+		return "", err
+	}
+
+	filename := path.Clean(b.String())
+	if b.Len() == 0 || path.IsAbs(filename) || filename == "." || filename == ".." || strings.HasPrefix(filename, "../") {
+		return "", fmt.Errorf(
+			"filename '%s' of pipeline '%s' is not a file within the destination",
+			b.String(),
+			concourse.VersionKey(teamName, pipelineName),
+		)
+	}
+
+	return filename, nil
+}
@@ -0,0 +1,31 @@
+package in
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// manifestFilename is the name of the manifest written to the destination
+// alongside the configs.
+const manifestFilename = "pipelines.json"
+
+// manifestEntry describes a config written to the destination.
+type manifestEntry struct {
+	Team         string                 `json:"team"`
+	Name         string                 `json:"name"`
+	InstanceVars map[string]interface{} `json:"instance_vars,omitempty"`
+	Version      string                 `json:"version"`
+	File         string                 `json:"file"`
+	StatusFile   string                 `json:"status_file,omitempty"`
+}
+
+func (c *Command) writeManifest(entries []manifestEntry) error {
+	manifest, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		// Untested as the manifest always marshals to JSON
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(c.downloadDir, manifestFilename), append(manifest, '\n'), filePerm)
+}
@@ -0,0 +1,51 @@
+package in
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+// selection is the pipelines which the params of a get select from those of
+// the teams in source.
+type selection struct {
+	teams     []string
+	pipelines concourse.PipelineFilter
+}
+
+func newSelection(params concourse.InParams) selection {
+	return selection{
+		teams:     params.Teams,
+		pipelines: concourse.PipelineFilter{Include: params.Pipelines},
+	}
+}
+
+// team returns true if the pipelines of the team are selected. The team of
+// a pipeline in a legacy version which is no longer current is unknown, and
+// is only selected if every team is.
+func (s selection) team(teamName string) bool {
+	if len(s.teams) == 0 {
+		return true
+	}
+
+	for _, t := range s.teams {
+		if t == teamName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches returns true if the pipeline of the team is selected. Every
+// instance of an instanced pipeline is matched by its name.
+func (s selection) matches(teamName string, pipelineName string) (bool, error) {
+	if !s.team(teamName) {
+		return false, nil
+	}
+
+	ref, err := concourse.ParsePipelineRef(pipelineName)
+	if err != nil {
+		return false, err
+	}
+
+	return s.pipelines.Matches(ref.Name)
+}
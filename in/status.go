@@ -0,0 +1,204 @@
+package in
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/fly"
+	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/pool"
+)
+
+// statusSuffix replaces the extension of a config's filename to give the
+// filename of its status.
+const statusSuffix = ".status.json"
+
+// pipelineStatus is the runtime state of a pipeline, written alongside its
+// config. Times are in RFC 3339 format, and are left out if unknown.
+type pipelineStatus struct {
+	Team         string                 `json:"team"`
+	Name         string                 `json:"name"`
+	InstanceVars map[string]interface{} `json:"instance_vars,omitempty"`
+	Paused       bool                   `json:"paused"`
+	Public       bool                   `json:"public"`
+	Archived     bool                   `json:"archived"`
+	LastUpdated  string                 `json:"last_updated,omitempty"`
+	Jobs         []jobStatus            `json:"jobs"`
+}
+
+type jobStatus struct {
+	Name          string       `json:"name"`
+	Paused        bool         `json:"paused"`
+	FinishedBuild *buildStatus `json:"finished_build,omitempty"`
+	NextBuild     *buildStatus `json:"next_build,omitempty"`
+}
+
+type buildStatus struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	StartTime string `json:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty"`
+}
+
+// statusFilename returns the filename of the status of the config written
+// to filename.
+func statusFilename(filename string) string {
+	return strings.TrimSuffix(filename, path.Ext(filename)) + statusSuffix
+}
+
+// getStatuses returns the status of each of pipelines which is one of the
+// current pipelines in refs. The status of a pipeline which no longer exists
+// is nil. The state of the pipelines of each team is listed once.
+func (c *Command) getStatuses(
+	concurrency int,
+	refs []pipelineRef,
+	pipelines []pipelineConfig,
+) ([]*pipelineStatus, error) {
+	current := make(map[pipelineRef]bool)
+	var teamNames []string
+	for _, r := range refs {
+		if !concourse.StringContains(teamNames, r.teamName) {
+			teamNames = append(teamNames, r.teamName)
+		}
+		current[r] = true
+	}
+
+	teamStates := make([]map[string]fly.PipelineState, len(teamNames))
+
+	err := pool.Run(concurrency, len(teamNames), func(i int) error {
+		states, err := c.flyCommand.PipelineStates(teamNames[i])
+		if err != nil {
+			return err
+		}
+
+		teamStates[i] = make(map[string]fly.PipelineState, len(states))
+		for _, state := range states {
+			teamStates[i][state.Ref()] = state
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[pipelineRef]fly.PipelineState)
+	for i, teamName := range teamNames {
+		for name, state := range teamStates[i] {
+			states[pipelineRef{teamName: teamName, pipelineName: name}] = state
+		}
+	}
+
+	statuses := make([]*pipelineStatus, len(pipelines))
+
+	err = pool.Run(concurrency, len(pipelines), func(i int) error {
+		r := pipelines[i].pipelineRef
+		if !current[r] {
+			c.logger.Debugf("Skipping status of pipeline which no longer exists: %s/%s\n", r.teamName, r.pipelineName)
+			return nil
+		}
+
+		state, found := states[r]
+		if !found {
+			return fmt.Errorf("pipeline '%s' (team '%s') not found", r.pipelineName, r.teamName)
+		}
+
+		status, err := c.getStatus(r, state)
+		if err != nil {
+			return err
+		}
+
+		statuses[i] = &status
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// getStatus returns the current runtime state of the pipeline, given the
+// state of the pipeline itself.
+func (c *Command) getStatus(r pipelineRef, state fly.PipelineState) (pipelineStatus, error) {
+	start := time.Now()
+
+	jobs, err := c.flyCommand.Jobs(r.teamName, r.pipelineName)
+	if err != nil {
+		return pipelineStatus{}, err
+	}
+
+	c.logger.With(
+		logger.Team(r.teamName),
+		logger.Pipeline(r.pipelineName),
+		logger.Operation("get-pipeline-status"),
+		logger.Duration(time.Since(start)),
+	).Infof("Fetched pipeline status\n")
+
+	ref, err := concourse.ParsePipelineRef(r.pipelineName)
+	if err != nil {
+		// Untested as the state would have failed to be fetched
+		return pipelineStatus{}, err
+	}
+
+	status := pipelineStatus{
+		Team:         r.teamName,
+		Name:         ref.Name,
+		InstanceVars: ref.InstanceVars,
+		Paused:       state.Paused,
+		Public:       state.Public,
+		Archived:     state.Archived,
+		LastUpdated:  formatTime(state.LastUpdated),
+		Jobs:         make([]jobStatus, len(jobs)),
+	}
+
+	for i, j := range jobs {
+		status.Jobs[i] = jobStatus{
+			Name:          j.Name,
+			Paused:        j.Paused,
+			FinishedBuild: newBuildStatus(j.FinishedBuild),
+			NextBuild:     newBuildStatus(j.NextBuild),
+		}
+	}
+
+	return status, nil
+}
+
+func newBuildStatus(b *fly.Build) *buildStatus {
+	if b == nil {
+		return nil
+	}
+
+	return &buildStatus{
+		Name:      b.Name,
+		Status:    b.Status,
+		StartTime: formatTime(b.StartTime),
+		EndTime:   formatTime(b.EndTime),
+	}
+}
+
+// formatTime formats seconds since the epoch, of which zero is unknown.
+func formatTime(seconds int64) string {
+	if seconds == 0 {
+		return ""
+	}
+
+	return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+}
+
+func (c *Command) writeStatus(filename string, status pipelineStatus) error {
+	contents, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		// Untested as a status always marshals to JSON
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(c.downloadDir, filepath.FromSlash(filename)), append(contents, '\n'), filePerm)
+}
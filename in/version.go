@@ -0,0 +1,156 @@
+package in
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/store"
+)
+
+// pipelineConfig is the config of a pipeline of the requested version.
+type pipelineConfig struct {
+	pipelineRef
+	config []byte
+
+	// restored is true if the config was fetched from the archive as the
+	// pipeline's current config is of another version.
+	restored bool
+}
+
+// resolveVersion returns the config of each selected pipeline of version,
+// given the current configs of the pipelines in refs, which are the current
+// selected pipelines. A current config which is not of
+// the requested version is replaced by that version's config from the
+// archive, so that a version is fetched as it was rather than as its
+// pipelines are now. Pipelines which are not part of version are left out.
+//
+// Every current pipeline is returned if version is empty, or if there is no
+// archive, in which case a warning is logged for each pipeline of version
+// which is not current.
+func (c *Command) resolveVersion(
+	version concourse.Version,
+	selected selection,
+	refs []pipelineRef,
+	configs [][]byte,
+) ([]pipelineConfig, error) {
+	if len(version) == 0 {
+		return currentConfigs(refs, configs), nil
+	}
+
+	legacy := version.IsLegacy()
+
+	current := make(map[string]int)
+	for i, r := range refs {
+		current[concourse.VersionKey(r.teamName, r.pipelineName)] = i
+
+		// Legacy versions are keyed by pipeline name alone, which is only
+		// emitted if the name is unique across teams.
+		if legacy {
+			current[r.pipelineName] = i
+		}
+	}
+
+	keys := make([]string, 0, len(version))
+	for key := range version {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pipelines := make([]pipelineConfig, 0, len(keys))
+
+	for _, key := range keys {
+		requested := version[key]
+		teamName, pipelineName := concourse.ParseVersionKey(key)
+
+		i, found := current[key]
+		if found {
+			// The team of a pipeline in a legacy version is that of the
+			// current pipeline.
+			teamName = refs[i].teamName
+		}
+
+		matched, err := selected.matches(teamName, pipelineName)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matched {
+			c.logger.Debugf("Skipping pipeline which is not selected: %s\n", key)
+			continue
+		}
+
+		reason := "no longer exists"
+		if found {
+			actual := store.Version(configs[i])
+			if actual == requested {
+				pipelines = append(pipelines, pipelineConfig{pipelineRef: refs[i], config: configs[i]})
+				continue
+			}
+
+			reason = fmt.Sprintf("is at version %s", actual)
+		}
+
+		if c.archive == nil {
+			c.logger.Warnf(
+				"Pipeline '%s' %s rather than the requested version %s, and no archive is configured to fetch it from\n",
+				key,
+				reason,
+				requested,
+			)
+			continue
+		}
+
+		if teamName == "" {
+			return nil, fmt.Errorf(
+				"pipeline '%s' %s rather than the requested version %s, which can not be fetched from the archive as the version does not name its team",
+				key,
+				reason,
+				requested,
+			)
+		}
+
+		c.logger.Debugf("Fetching pipeline from archive: %s/%s@%s\n", teamName, pipelineName, requested)
+		config, err := c.archive.Get(teamName, pipelineName, requested)
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf(
+				"pipeline '%s' %s rather than the requested version %s, which was not found in the archive",
+				key,
+				reason,
+				requested,
+			)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.logger.With(
+			logger.Team(teamName),
+			logger.Pipeline(pipelineName),
+			logger.Operation("get-pipeline"),
+		).Infof("Restored pipeline config of version %s from archive\n", requested)
+
+		pipelines = append(pipelines, pipelineConfig{
+			pipelineRef: pipelineRef{teamName: teamName, pipelineName: pipelineName},
+			config:      config,
+			restored:    true,
+		})
+	}
+
+	if c.archive == nil {
+		// Without an archive, the current configs are fetched, as they were
+		// before versions were verified.
+		return currentConfigs(refs, configs), nil
+	}
+
+	return pipelines, nil
+}
+
+func currentConfigs(refs []pipelineRef, configs [][]byte) []pipelineConfig {
+	pipelines := make([]pipelineConfig, len(refs))
+	for i, r := range refs {
+		pipelines[i] = pipelineConfig{pipelineRef: r, config: configs[i]}
+	}
+	return pipelines
+}
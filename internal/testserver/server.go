@@ -0,0 +1,177 @@
+// Package testserver provides an HTTP server for tests which verifies and
+// responds to each request with the next of a sequence of handlers.
+package testserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Server responds to the nth request it receives with its nth handler, and
+// fails the test if it receives more requests than it has handlers.
+type Server struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	handlers []http.HandlerFunc
+	requests []*http.Request
+}
+
+func NewServer() *Server {
+	s := &Server{}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// AppendHandlers adds handlers for the requests following those with
+// handlers already.
+func (s *Server) AppendHandlers(handlers ...http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers = append(s.handlers, handlers...)
+}
+
+// SetHandler replaces the handler of the ith request.
+func (s *Server) SetHandler(i int, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[i] = handler
+}
+
+// ReceivedRequests returns the requests received so far.
+func (s *Server) ReceivedRequests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*http.Request{}, s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	// A failed assertion panics, which GinkgoRecover reports as a failure of
+	// the test rather than leaving it to the HTTP server.
+	defer GinkgoRecover()
+	defer func() {
+		if e := recover(); e != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			panic(e)
+		}
+	}()
+
+	s.mu.Lock()
+	i := len(s.requests)
+	s.requests = append(s.requests, req)
+
+	var handler http.HandlerFunc
+	if i < len(s.handlers) {
+		handler = s.handlers[i]
+	}
+	s.mu.Unlock()
+
+	if handler == nil {
+		Fail(fmt.Sprintf("received unhandled request: %s %s", req.Method, req.URL))
+	}
+
+	handler(w, req)
+}
+
+// CombineHandlers returns a handler which calls each of handlers in turn.
+func CombineHandlers(handlers ...http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		for _, h := range handlers {
+			h(w, req)
+		}
+	}
+}
+
+// VerifyRequest verifies the method and path of the request and, if
+// provided, its raw query.
+func VerifyRequest(method string, path string, rawQuery ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		Expect(req.Method).To(Equal(method), "Method mismatch")
+		Expect(req.URL.Path).To(Equal(path), "Path mismatch")
+		if len(rawQuery) > 0 {
+			Expect(req.URL.RawQuery).To(Equal(rawQuery[0]), "Query mismatch")
+		}
+	}
+}
+
+func VerifyBasicAuth(username string, password string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		u, p, ok := req.BasicAuth()
+		Expect(ok).To(BeTrue(), "Basic auth not provided")
+		Expect(u).To(Equal(username), "Username mismatch")
+		Expect(p).To(Equal(password), "Password mismatch")
+	}
+}
+
+func VerifyHeaderKV(key string, values ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		Expect(req.Header[http.CanonicalHeaderKey(key)]).To(Equal(values), "Header mismatch for %s", key)
+	}
+}
+
+func VerifyFormKV(key string, values ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		Expect(req.ParseForm()).To(Succeed())
+		Expect(req.Form[key]).To(Equal(values), "Form mismatch for %s", key)
+	}
+}
+
+func VerifyBody(expected []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).To(Equal(expected), "Body mismatch")
+	}
+}
+
+func VerifyJSON(expected string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).To(MatchJSON(expected), "JSON mismatch")
+	}
+}
+
+// RespondWith responds with the status code, body and, if provided, header.
+// body is a string, []byte or a value which is marshalled to JSON.
+func RespondWith(statusCode int, body interface{}, header ...http.Header) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		for _, h := range header {
+			for k, v := range h {
+				w.Header()[k] = v
+			}
+		}
+
+		w.WriteHeader(statusCode)
+
+		switch b := body.(type) {
+		case string:
+			w.Write([]byte(b))
+		case []byte:
+			w.Write(b)
+		case nil:
+		default:
+			j, err := json.Marshal(b)
+			Expect(err).NotTo(HaveOccurred())
+			w.Write(j)
+		}
+	}
+}
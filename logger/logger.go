@@ -1,26 +1,243 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
+	"time"
 )
 
 //go:generate counterfeiter . Logger
 
 type Logger interface {
 	Debugf(format string, a ...interface{}) (n int, err error)
+	Infof(format string, a ...interface{}) (n int, err error)
+	Warnf(format string, a ...interface{}) (n int, err error)
+	Errorf(format string, a ...interface{}) (n int, err error)
+
+	// With returns a logger which adds fields to every line it logs.
+	With(fields ...Field) Logger
+}
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name. An empty name is LevelDebug, so that the
+// log file contains everything unless configured otherwise.
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return LevelDebug, nil
+	}
+
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+
+	return LevelDebug, fmt.Errorf("unknown log level: %s", name)
+}
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses a format name. An empty name is FormatText.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return FormatText, fmt.Errorf("unknown log format: %s", name)
+	}
+}
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func Team(name string) Field {
+	return Field{Key: "team", Value: name}
+}
+
+func Pipeline(name string) Field {
+	return Field{Key: "pipeline", Value: name}
+}
+
+func Operation(name string) Field {
+	return Field{Key: "operation", Value: name}
+}
+
+func Duration(d time.Duration) Field {
+	return Field{Key: "duration", Value: d.String()}
+}
+
+type Options struct {
+	// Level is the minimum level written to the sink.
+	Level Level
+
+	// Format is the format of lines written to the sink.
+	Format Format
+
+	// Tee, if provided, receives a human-readable copy of every line at
+	// LevelInfo or above, regardless of Format.
+	Tee io.Writer
 }
 
 type logger struct {
-	sink io.Writer
+	sink    io.Writer
+	options Options
+	fields  []Field
+
+	// mutex is shared between loggers created by With, as they write to the
+	// same sink.
+	mutex *sync.Mutex
 }
 
+// NewLogger returns a logger which writes every level to sink as text.
 func NewLogger(sink io.Writer) Logger {
+	return New(sink, Options{})
+}
+
+func New(sink io.Writer, options Options) Logger {
+	if options.Format == "" {
+		options.Format = FormatText
+	}
+
 	return &logger{
-		sink: sink,
+		sink:    sink,
+		options: options,
+		mutex:   &sync.Mutex{},
 	}
 }
 
 func (l logger) Debugf(format string, a ...interface{}) (int, error) {
-	return fmt.Fprintf(l.sink, format, a...)
+	return l.log(LevelDebug, format, a...)
+}
+
+func (l logger) Infof(format string, a ...interface{}) (int, error) {
+	return l.log(LevelInfo, format, a...)
+}
+
+func (l logger) Warnf(format string, a ...interface{}) (int, error) {
+	return l.log(LevelWarn, format, a...)
+}
+
+func (l logger) Errorf(format string, a ...interface{}) (int, error) {
+	return l.log(LevelError, format, a...)
+}
+
+func (l logger) With(fields ...Field) Logger {
+	withFields := make([]Field, 0, len(l.fields)+len(fields))
+	withFields = append(withFields, l.fields...)
+	withFields = append(withFields, fields...)
+
+	return &logger{
+		sink:    l.sink,
+		options: l.options,
+		fields:  withFields,
+		mutex:   l.mutex,
+	}
+}
+
+func (l logger) log(level Level, format string, a ...interface{}) (int, error) {
+	message := fmt.Sprintf(format, a...)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.options.Tee != nil && level >= LevelInfo && level >= l.options.Level {
+		// The tee is best-effort; failing to write progress must not fail
+		// the step.
+		l.options.Tee.Write(l.text(level, message))
+	}
+
+	if level < l.options.Level {
+		return 0, nil
+	}
+
+	var line []byte
+	switch l.options.Format {
+	case FormatJSON:
+		var err error
+		line, err = l.json(level, message)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		line = l.text(level, message)
+	}
+
+	return l.sink.Write(line)
+}
+
+// text formats a line as the message, prefixed by its level unless it is a
+// debug line, and followed by the fields as key=value pairs.
+func (l logger) text(level Level, message string) []byte {
+	var b bytes.Buffer
+
+	if level != LevelDebug {
+		b.WriteString(strings.ToUpper(level.String()))
+		b.WriteString(": ")
+	}
+
+	if len(l.fields) == 0 {
+		b.WriteString(message)
+		return b.Bytes()
+	}
+
+	b.WriteString(strings.TrimRight(message, "\n"))
+	for _, f := range l.fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	b.WriteString("\n")
+
+	return b.Bytes()
+}
+
+// json formats a line as a single JSON object holding the time, level,
+// message and fields.
+func (l logger) json(level Level, message string) ([]byte, error) {
+	entry := make(map[string]interface{}, len(l.fields)+3)
+	for _, f := range l.fields {
+		entry[f.Key] = f.Value
+	}
+
+	// Fields never replace the time, level or message.
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["message"] = strings.TrimRight(message, "\n")
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
 }
@@ -1,10 +1,13 @@
 package logger_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/concourse/concourse-pipeline-resource/logger"
@@ -61,4 +64,122 @@ var _ = Describe("Logger", func() {
 			})
 		})
 	})
+
+	Describe("New", func() {
+		var (
+			sink    *bytes.Buffer
+			tee     *bytes.Buffer
+			options logger.Options
+		)
+
+		BeforeEach(func() {
+			sink = &bytes.Buffer{}
+			tee = &bytes.Buffer{}
+			options = logger.Options{}
+		})
+
+		JustBeforeEach(func() {
+			l = logger.New(sink, options)
+		})
+
+		It("prefixes lines above debug with their level", func() {
+			l.Debugf("some debug\n")
+			l.Infof("some info\n")
+			l.Warnf("some warning\n")
+			l.Errorf("some error\n")
+
+			Expect(sink.String()).To(Equal("some debug\nINFO: some info\nWARN: some warning\nERROR: some error\n"))
+		})
+
+		It("appends fields as key=value pairs", func() {
+			l.With(logger.Team("some-team")).With(
+				logger.Pipeline("some-pipeline"),
+				logger.Operation("set-pipeline"),
+				logger.Duration(1500*time.Millisecond),
+			).Infof("pipeline set\n")
+
+			Expect(sink.String()).To(Equal("INFO: pipeline set team=some-team pipeline=some-pipeline operation=set-pipeline duration=1.5s\n"))
+		})
+
+		Context("when a level is configured", func() {
+			BeforeEach(func() {
+				options.Level = logger.LevelWarn
+			})
+
+			It("drops lines below the level", func() {
+				l.Debugf("some debug\n")
+				l.Infof("some info\n")
+				l.Warnf("some warning\n")
+
+				Expect(sink.String()).To(Equal("WARN: some warning\n"))
+			})
+		})
+
+		Context("when the format is json", func() {
+			BeforeEach(func() {
+				options.Format = logger.FormatJSON
+			})
+
+			It("writes a JSON object per line", func() {
+				l.With(logger.Team("some-team"), logger.Field{Key: "level", Value: "overridden"}).Infof("some info\n")
+
+				var entry map[string]interface{}
+				err := json.Unmarshal(sink.Bytes(), &entry)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(entry["level"]).To(Equal("info"))
+				Expect(entry["message"]).To(Equal("some info"))
+				Expect(entry["team"]).To(Equal("some-team"))
+				Expect(entry["time"]).NotTo(BeEmpty())
+			})
+		})
+
+		Context("when a tee is provided", func() {
+			BeforeEach(func() {
+				options.Format = logger.FormatJSON
+				options.Tee = tee
+			})
+
+			It("writes info lines and above to the tee as text", func() {
+				l.Debugf("some debug\n")
+				l.With(logger.Team("some-team")).Infof("some info\n")
+				l.Errorf("some error\n")
+
+				Expect(tee.String()).To(Equal("INFO: some info team=some-team\nERROR: some error\n"))
+				Expect(strings.Count(sink.String(), "\n")).To(Equal(3))
+			})
+		})
+	})
+
+	Describe("ParseLevel", func() {
+		It("defaults to debug", func() {
+			level, err := logger.ParseLevel("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(level).To(Equal(logger.LevelDebug))
+		})
+
+		It("parses level names", func() {
+			level, err := logger.ParseLevel("warn")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(level).To(Equal(logger.LevelWarn))
+		})
+
+		It("returns an error for unknown levels", func() {
+			_, err := logger.ParseLevel("verbose")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ParseFormat", func() {
+		It("defaults to text", func() {
+			format, err := logger.ParseFormat("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(format).To(Equal(logger.FormatText))
+		})
+
+		It("returns an error for unknown formats", func() {
+			_, err := logger.ParseFormat("xml")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
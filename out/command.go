@@ -3,13 +3,19 @@ package out
 import (
 	"crypto/md5"
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/diff"
 	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/logger"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	"github.com/concourse/concourse-pipeline-resource/render"
+	"github.com/concourse/concourse-pipeline-resource/store"
 )
 
 const (
@@ -19,22 +25,40 @@ const (
 type Command struct {
 	logger     logger.Logger
 	flyCommand fly.Command
+	archive    store.Store
 	sourcesDir string
+	stderr     io.Writer
+	secrets    *redact.Secrets
 }
 
+// NewCommand returns a command which reports its progress to stderr. Secrets
+// found in the request are added to secrets before anything is logged, so
+// the logger sink and stderr should both redact them. If archive is not nil,
+// the config of every pipeline which is set is stored in it.
 func NewCommand(
 	logger logger.Logger,
 	flyCommand fly.Command,
+	archive store.Store,
 	sourcesDir string,
+	stderr io.Writer,
+	secrets *redact.Secrets,
 ) *Command {
 	return &Command{
 		logger:     logger,
 		flyCommand: flyCommand,
+		archive:    archive,
 		sourcesDir: sourcesDir,
+		stderr:     &syncWriter{sink: stderr},
+		secrets:    secrets,
 	}
 }
 
 func (c *Command) Run(input concourse.OutRequest) (concourse.OutResponse, error) {
+	err := c.registerSecrets(input)
+	if err != nil {
+		return concourse.OutResponse{}, err
+	}
+
 	c.logger.Debugf("Received input: %+v\n", input)
 
 	insecure := false
@@ -56,98 +80,203 @@ func (c *Command) Run(input concourse.OutRequest) (concourse.OutResponse, error)
 
 	c.logger.Debugf("Input pipelines: %+v\n", pipelines)
 
-	c.logger.Debugf("Setting pipelines\n")
+	if input.Params.DryRun {
+		return c.dryRun(input, teams, insecure)
+	}
+
+	logins := c.newLogins(input.Source.Target, teams, insecure)
+
+	// The state of the pipelines which exist in each team, so that they can
+	// be compared with the declared pipelines.
+	existingPipelines := make(map[string]map[string]fly.PipelineState)
+
+	// Log in to every team up front, so that the pipelines of a team can be
+	// set concurrently with a single login.
 	for _, p := range pipelines {
-		team, found := teams[p.TeamName]
-		if !found {
+		if _, found := teams[p.TeamName]; !found {
 			return concourse.OutResponse{}, fmt.Errorf("team (%s) configuration not found for pipeline (%s)", p.TeamName, p.Name)
 		}
 
-		c.logger.Debugf("Performing login\n")
-		_, err := c.flyCommand.Login(
-			input.Source.Target,
-			p.TeamName,
-			team.Username,
-			team.Password,
-			insecure,
-		)
-		if err != nil {
-			return concourse.OutResponse{}, err
+		if _, found := existingPipelines[p.TeamName]; found {
+			continue
 		}
 
-		c.logger.Debugf("Login successful\n")
-
-		configFilepath := filepath.Join(c.sourcesDir, p.ConfigFile)
-
-		var varsFilepaths []string
-		for _, v := range p.VarsFiles {
-			varFilepath := filepath.Join(c.sourcesDir, v)
-			varsFilepaths = append(varsFilepaths, varFilepath)
+		err := logins.login(p.TeamName)
+		if err != nil {
+			return concourse.OutResponse{}, err
 		}
 
-		var setOutput []byte
-		setOutput, err = c.flyCommand.SetPipeline(p.Name, configFilepath, varsFilepaths, p.Vars)
-		c.logger.Debugf("pipeline '%s' set; output:\n\n%s\n", p.Name, string(setOutput))
-		fmt.Fprintf(os.Stderr, "pipeline '%s' set; output:\n\n%s\n", p.Name, string(setOutput))
+		states, err := c.flyCommand.PipelineStates(p.TeamName)
 		if err != nil {
 			return concourse.OutResponse{}, err
 		}
 
-		if p.Exposed {
-			_, err = c.flyCommand.ExposePipeline(p.Name)
-			if err != nil {
-				return concourse.OutResponse{}, err
-			}
+		existingPipelines[p.TeamName] = make(map[string]fly.PipelineState)
+		for _, state := range states {
+			existingPipelines[p.TeamName][state.Ref()] = state
 		}
+	}
 
-		if p.Unpaused {
-			_, err = c.flyCommand.UnpausePipeline(p.Name)
-			if err != nil {
-				return concourse.OutResponse{}, err
-			}
+	var previousMutex sync.Mutex
+	previousVersions := make(map[string]string)
+
+	set := func(p concourse.Pipeline) (string, error) {
+		status, previousVersion, err := c.applyPipeline(p, existingPipelines[p.TeamName])
+		if previousVersion != "" {
+			previousMutex.Lock()
+			previousVersions[concourse.VersionKey(p.TeamName, p.Ref().String())] = previousVersion
+			previousMutex.Unlock()
 		}
+
+		return status, err
 	}
+
+	failFast := input.Params.FailFast == nil || *input.Params.FailFast
+
+	c.logger.Debugf("Setting pipelines\n")
+	results := schedule(pipelines, input.Params.Concurrency, failFast, set)
+	setErr := c.summarize(results, failFast)
 	c.logger.Debugf("Setting pipelines complete\n")
 
+	// Pipelines are not pruned unless every pipeline was set, as a pipeline
+	// which failed may have been meant to replace one which would be pruned.
+	if setErr == nil && len(input.Params.Prune) > 0 {
+		c.logger.Debugf("Pruning pipelines\n")
+		err := c.prune(input, logins, false)
+		if err != nil {
+			return concourse.OutResponse{}, err
+		}
+		c.logger.Debugf("Pruning pipelines complete\n")
+	}
+
 	pipelineVersions := make(map[string]string)
 
-	for teamName, team := range teams {
-		c.logger.Debugf("Performing login\n")
-		_, err := c.flyCommand.Login(
-			input.Source.Target,
-			teamName,
-			team.Username,
-			team.Password,
-			insecure,
-		)
+	for _, r := range results {
+		if r.Status != statusSucceeded && r.Status != statusUnchanged {
+			// Archived pipelines, and those which failed, have no version.
+			continue
+		}
+
+		ref := r.Pipeline.Ref().String()
+
+		c.logger.Debugf("Getting pipeline: %s\n", ref)
+		outBytes, err := c.flyCommand.GetPipeline(r.Pipeline.TeamName, ref)
 		if err != nil {
 			return concourse.OutResponse{}, err
 		}
 
-		c.logger.Debugf("Login successful\n")
+		version := fmt.Sprintf(
+			"%x",
+			md5.Sum(outBytes),
+		)
+		pipelineVersions[concourse.VersionKey(r.Pipeline.TeamName, ref)] = version
 
-		for _, pipeline := range pipelines {
-			if pipeline.TeamName != teamName {
-				continue
-			}
-			c.logger.Debugf("Getting pipeline: %s\n", pipeline.Name)
-			outBytes, err := c.flyCommand.GetPipeline(pipeline.Name)
+		if c.archive != nil {
+			c.logger.Debugf("Archiving pipeline: %s\n", ref)
+			err = c.archive.Put(r.Pipeline.TeamName, ref, outBytes)
 			if err != nil {
 				return concourse.OutResponse{}, err
 			}
-
-			version := fmt.Sprintf(
-				"%x",
-				md5.Sum(outBytes),
-			)
-			pipelineVersions[pipeline.Name] = version
 		}
 	}
 
+	metadata, err := pipelineMetadata(input.Source.Target, results, previousVersions, pipelineVersions)
+	if err != nil {
+		return concourse.OutResponse{}, err
+	}
+
 	response := concourse.OutResponse{
 		Version:  pipelineVersions,
-		Metadata: []concourse.Metadata{},
+		Metadata: metadata,
 	}
 
-	return response, nil
+	return response, setErr
+}
+
+// setPipeline sets a pipeline of a team which has been logged in to. It may
+// be called concurrently.
+//
+// If the pipeline exists its current config is given, and the pipeline is
+// only set if the config would change, returning statusUnchanged otherwise.
+func (c *Command) setPipeline(p concourse.Pipeline, current []byte) (string, error) {
+	configFilepath, cleanup, err := c.configFilepath(p)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	ref := p.Ref().String()
+
+	var varsFilepaths []string
+	for _, v := range p.VarsFiles {
+		varFilepath := filepath.Join(c.sourcesDir, v)
+		varsFilepaths = append(varsFilepaths, varFilepath)
+	}
+
+	start := time.Now()
+	status := statusSucceeded
+	message := "Pipeline set\n"
+
+	if current != nil && c.unchanged(p, current, configFilepath, varsFilepaths) {
+		status = statusUnchanged
+		message = "Pipeline unchanged\n"
+		c.logger.Debugf("pipeline '%s' unchanged; not set\n", ref)
+		fmt.Fprintf(c.stderr, "pipeline '%s' unchanged; not set\n", ref)
+	} else {
+		var setOutput []byte
+		setOutput, err = c.flyCommand.SetPipeline(p.TeamName, ref, configFilepath, varsFilepaths, p.Vars)
+		c.logger.Debugf("pipeline '%s' set; output:\n\n%s\n", ref, string(setOutput))
+		fmt.Fprintf(c.stderr, "pipeline '%s' set; output:\n\n%s\n", ref, string(setOutput))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	c.logger.With(
+		logger.Team(p.TeamName),
+		logger.Pipeline(ref),
+		logger.Operation("set-pipeline"),
+		logger.Duration(time.Since(start)),
+	).Infof(message)
+
+	return status, nil
+}
+
+// unchanged returns true if interpolating the config locally gives a config
+// which is semantically identical to the current one. Any error is treated
+// as a change, leaving fly to set, and report on, the config.
+func (c *Command) unchanged(
+	p concourse.Pipeline,
+	current []byte,
+	configFilepath string,
+	varsFilepaths []string,
+) bool {
+	ref := p.Ref()
+
+	proposed, err := render.Config(configFilepath, varsFilepaths, ref.Vars(p.Vars))
+	if err != nil {
+		c.logger.Debugf("Failed to interpolate pipeline '%s' locally; setting it: %v\n", ref, err)
+		return false
+	}
+
+	equal, err := diff.Equal(current, proposed)
+	if err != nil {
+		c.logger.Debugf("Failed to compare pipeline '%s' with its current config; setting it: %v\n", ref, err)
+		return false
+	}
+
+	return equal
+}
+
+// syncWriter serializes writes from pipelines which are set concurrently.
+type syncWriter struct {
+	mutex sync.Mutex
+	sink  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.sink.Write(p)
 }
@@ -1,18 +1,25 @@
 package out_test
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/fly"
 	"github.com/concourse/concourse-pipeline-resource/fly/flyfakes"
 	"github.com/concourse/concourse-pipeline-resource/logger"
 	"github.com/concourse/concourse-pipeline-resource/out"
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	"github.com/concourse/concourse-pipeline-resource/store"
+	"github.com/concourse/concourse-pipeline-resource/store/storefakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/robdimsdale/sanitizer"
 )
 
 var _ = Describe("Out", func() {
@@ -20,6 +27,8 @@ var _ = Describe("Out", func() {
 		sourcesDir string
 
 		ginkgoLogger logger.Logger
+		logContents  *bytes.Buffer
+		stderr       *bytes.Buffer
 
 		target        string
 		username      string
@@ -40,10 +49,12 @@ var _ = Describe("Out", func() {
 		command       *out.Command
 
 		fakeFlyCommand *flyfakes.FakeCommand
+		archive        store.Store
 	)
 
 	BeforeEach(func() {
 		fakeFlyCommand = &flyfakes.FakeCommand{}
+		archive = nil
 
 		var err error
 		sourcesDir, err = ioutil.TempDir("", "")
@@ -101,7 +112,7 @@ pipeline3: foo
 			},
 		}
 
-		fakeFlyCommand.GetPipelineStub = func(name string) ([]byte, error) {
+		fakeFlyCommand.GetPipelineStub = func(teamName string, name string) ([]byte, error) {
 			defer GinkgoRecover()
 			ginkgoLogger.Debugf("GetPipelineStub for: %s\n", name)
 
@@ -159,14 +170,28 @@ pipeline3: foo
 	JustBeforeEach(func() {
 		fakeFlyCommand.SetPipelineReturns(nil, setPipelinesErr)
 
-		sanitized := concourse.SanitizedSource(outRequest.Source)
-		sanitizer := sanitizer.NewSanitizer(sanitized, GinkgoWriter)
+		secrets := redact.NewSecrets()
 
-		ginkgoLogger = logger.NewLogger(sanitizer)
+		logContents = &bytes.Buffer{}
+		ginkgoLogger = logger.NewLogger(redact.NewWriter(secrets, io.MultiWriter(GinkgoWriter, logContents)))
 
-		command = out.NewCommand(ginkgoLogger, fakeFlyCommand, sourcesDir)
+		stderr = &bytes.Buffer{}
+
+		command = out.NewCommand(ginkgoLogger, fakeFlyCommand, archive, sourcesDir, redact.NewWriter(secrets, stderr), secrets)
 	})
 
+	pipelineMetadata := func(response concourse.OutResponse) []concourse.PipelineMetadata {
+		var metadata []concourse.PipelineMetadata
+		for _, m := range response.Metadata {
+			var pm concourse.PipelineMetadata
+			Expect(json.Unmarshal([]byte(m.Value), &pm)).To(Succeed())
+			ref := concourse.PipelineRef{Name: pm.Name, InstanceVars: pm.InstanceVars}
+			Expect(m.Name).To(Equal(pm.Team + "/" + ref.String()))
+			metadata = append(metadata, pm)
+		}
+		return metadata
+	}
+
 	AfterEach(func() {
 		err := os.RemoveAll(sourcesDir)
 		Expect(err).NotTo(HaveOccurred())
@@ -179,10 +204,9 @@ pipeline3: foo
 		Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(len(pipelines)))
 
 		for i, p := range pipelines {
-			name, configFilepath, varsFilepaths, vars := fakeFlyCommand.SetPipelineArgsForCall(i)
-			_, tname, _, _, _ := fakeFlyCommand.LoginArgsForCall(i)
+			steam, name, configFilepath, varsFilepaths, vars := fakeFlyCommand.SetPipelineArgsForCall(i)
 			Expect(name).To(Equal(p.Name))
-			Expect(tname).To(Equal(p.TeamName))
+			Expect(steam).To(Equal(p.TeamName))
 			Expect(configFilepath).To(Equal(filepath.Join(sourcesDir, p.ConfigFile)))
 
 			// the first pipeline has vars files
@@ -193,7 +217,8 @@ pipeline3: foo
 
 			// the second pipeline has Unpaused and Exposed set to true
 			if i == 1 {
-				name := fakeFlyCommand.UnpausePipelineArgsForCall(0)
+				uteam, name := fakeFlyCommand.UnpausePipelineArgsForCall(0)
+				Expect(uteam).To(Equal(p.TeamName))
 				Expect(name).To(Equal(p.Name))
 				Expect(fakeFlyCommand.UnpausePipelineCallCount()).To(Equal(1))
 				Expect(fakeFlyCommand.ExposePipelineCallCount()).To(Equal(1))
@@ -207,12 +232,141 @@ pipeline3: foo
 		}
 	})
 
+	It("logs in to each team once", func() {
+		_, err := command.Run(outRequest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fakeFlyCommand.LoginCallCount()).To(Equal(2))
+
+		_, tname, _, _ := fakeFlyCommand.LoginArgsForCall(0)
+		Expect(tname).To(Equal(teamName))
+
+		_, tname, _, _ = fakeFlyCommand.LoginArgsForCall(1)
+		Expect(tname).To(Equal(otherTeamName))
+	})
+
+	Context("when pipelines are set concurrently", func() {
+		var (
+			started  chan string
+			releases map[string]chan struct{}
+		)
+
+		BeforeEach(func() {
+			outRequest.Params.Concurrency = 3
+
+			started = make(chan string, len(pipelines))
+			releases = map[string]chan struct{}{}
+			for _, p := range pipelines {
+				releases[p.Name] = make(chan struct{})
+			}
+		})
+
+		JustBeforeEach(func() {
+			fakeFlyCommand.SetPipelineStub = func(teamName string, name string, _ string, _ []string, _ map[string]interface{}) ([]byte, error) {
+				started <- name
+				<-releases[name]
+				return nil, nil
+			}
+		})
+
+		run := func() chan error {
+			done := make(chan error, 1)
+			go func() {
+				_, err := command.Run(outRequest)
+				done <- err
+			}()
+			return done
+		}
+
+		It("sets up to concurrency pipelines at once", func() {
+			done := run()
+
+			var names []string
+			for range pipelines {
+				var name string
+				Eventually(started).Should(Receive(&name))
+				names = append(names, name)
+			}
+			Expect(names).To(ConsistOf(apiPipelines))
+
+			for _, release := range releases {
+				close(release)
+			}
+			Eventually(done).Should(Receive(BeNil()))
+		})
+
+		Context("when the limit is lower than the number of pipelines", func() {
+			BeforeEach(func() {
+				outRequest.Params.Concurrency = 2
+			})
+
+			It("waits for a pipeline to be set before starting another", func() {
+				done := run()
+
+				var names []string
+				for i := 0; i < 2; i++ {
+					var name string
+					Eventually(started).Should(Receive(&name))
+					names = append(names, name)
+				}
+				Expect(names).To(ConsistOf(apiPipelines[0], apiPipelines[1]))
+				Consistently(started).ShouldNot(Receive())
+
+				close(releases[apiPipelines[1]])
+				Eventually(started).Should(Receive(Equal(apiPipelines[2])))
+
+				close(releases[apiPipelines[0]])
+				close(releases[apiPipelines[2]])
+				Eventually(done).Should(Receive(BeNil()))
+			})
+		})
+
+		Context("when a pipeline depends on another", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[1].DependsOn = []string{apiPipelines[0]}
+			})
+
+			It("is not set until its dependency has been set", func() {
+				done := run()
+
+				var names []string
+				for i := 0; i < 2; i++ {
+					var name string
+					Eventually(started).Should(Receive(&name))
+					names = append(names, name)
+				}
+				Expect(names).To(ConsistOf(apiPipelines[0], apiPipelines[2]))
+				Consistently(started).ShouldNot(Receive())
+
+				close(releases[apiPipelines[0]])
+				Eventually(started).Should(Receive(Equal(apiPipelines[1])))
+
+				close(releases[apiPipelines[1]])
+				close(releases[apiPipelines[2]])
+				Eventually(done).Should(Receive(BeNil()))
+			})
+		})
+	})
+
+	It("logs each pipeline set at info level", func() {
+		_, err := command.Run(outRequest)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, p := range pipelines {
+			Expect(logContents.String()).To(MatchRegexp(
+				"INFO: Pipeline set team=%s pipeline=%s operation=set-pipeline duration=\\S+\n",
+				p.TeamName,
+				p.Name,
+			))
+		}
+	})
+
 	It("returns provided version", func() {
 		response, err := command.Run(outRequest)
 
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(response.Version[apiPipelines[0]]).To(Equal("4f4bd60b18bf697cc68dac9cb95537c2"))
+		Expect(response.Version[teamName+"/"+apiPipelines[0]]).To(Equal("4f4bd60b18bf697cc68dac9cb95537c2"))
 	})
 
 	It("returns metadata", func() {
@@ -220,7 +374,967 @@ pipeline3: foo
 
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(response.Metadata).NotTo(BeNil())
+		Expect(pipelineMetadata(response)).To(Equal([]concourse.PipelineMetadata{
+			{
+				Team:    teamName,
+				Name:    apiPipelines[0],
+				Status:  "succeeded",
+				Version: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[0]))),
+				Changed: true,
+				URL:     "some target/teams/main/pipelines/pipeline-1",
+			},
+			{
+				Team:    teamName,
+				Name:    apiPipelines[1],
+				Status:  "succeeded",
+				Version: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[1]))),
+				Changed: true,
+				URL:     "some target/teams/main/pipelines/pipeline-2",
+			},
+			{
+				Team:    otherTeamName,
+				Name:    apiPipelines[2],
+				Status:  "succeeded",
+				Version: fmt.Sprintf("%x", md5.Sum([]byte(pipelineContents[2]))),
+				Changed: true,
+				URL:     "some target/teams/some-other-team/pipelines/pipeline-3",
+			},
+		}))
+	})
+
+	Context("when an archive is configured", func() {
+		var fakeArchive *storefakes.FakeStore
+
+		BeforeEach(func() {
+			fakeArchive = &storefakes.FakeStore{}
+			archive = fakeArchive
+		})
+
+		It("stores the config of each pipeline which is set", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeArchive.PutCallCount()).To(Equal(len(pipelines)))
+			for i, p := range pipelines {
+				team, name, config := fakeArchive.PutArgsForCall(i)
+				Expect(team).To(Equal(p.TeamName))
+				Expect(name).To(Equal(p.Name))
+				Expect(string(config)).To(Equal(pipelineContents[i]))
+			}
+		})
+
+		Context("when storing a config returns an error", func() {
+			BeforeEach(func() {
+				fakeArchive.PutReturns(fmt.Errorf("archive unavailable"))
+			})
+
+			It("returns the error", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).To(MatchError("archive unavailable"))
+			})
+		})
+	})
+
+	Context("when the config of existing pipelines is unchanged", func() {
+		BeforeEach(func() {
+			fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+				{Name: apiPipelines[0]},
+				{Name: apiPipelines[1], Paused: true},
+			}, nil)
+
+			files := map[string]string{
+				// Formatted differently to the current config
+				"pipeline_1.yml": "pipeline1:    foo\n",
+				"vars_1.yml":     "",
+				"vars_2.yml":     "",
+				"pipeline_2.yml": "pipeline2: ((value))\n",
+			}
+			for name, contents := range files {
+				err := ioutil.WriteFile(filepath.Join(sourcesDir, name), []byte(contents), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			outRequest.Params.Pipelines[1].Vars = map[string]interface{}{"value": "foo"}
+		})
+
+		It("does not set them", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(1))
+			_, name, _, _, _ := fakeFlyCommand.SetPipelineArgsForCall(0)
+			Expect(name).To(Equal(apiPipelines[2]))
+
+			Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-1' unchanged; not set"))
+		})
+
+		It("still exposes and unpauses them", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.ExposePipelineCallCount()).To(Equal(1))
+			Expect(fakeFlyCommand.UnpausePipelineCallCount()).To(Equal(1))
+		})
+
+		It("reports them as unchanged", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			metadata := pipelineMetadata(response)
+			Expect(metadata[0].Status).To(Equal("unchanged"))
+			Expect(metadata[0].Changed).To(BeFalse())
+			Expect(metadata[1].Status).To(Equal("unchanged"))
+			Expect(metadata[2].Status).To(Equal("succeeded"))
+
+			Expect(stderr.String()).To(MatchRegexp(`%s\s+%s\s+unchanged\s*\n`, apiPipelines[0], teamName))
+		})
+
+		Context("when a config changes", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[1].Vars = map[string]interface{}{"value": "bar"}
+			})
+
+			It("sets it", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(2))
+				Expect(pipelineMetadata(response)[1].Status).To(Equal("succeeded"))
+			})
+		})
+
+		Context("when only the order of the groups of a config changes", func() {
+			BeforeEach(func() {
+				pipelineContents[0] = `---
+groups:
+- name: build
+  jobs: [build]
+- name: test
+  jobs: [test]
+`
+
+				err := ioutil.WriteFile(filepath.Join(sourcesDir, "pipeline_1.yml"), []byte(`---
+groups:
+- name: test
+  jobs: [test]
+- name: build
+  jobs: [build]
+`), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("sets it", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(2))
+				_, name, _, _, _ := fakeFlyCommand.SetPipelineArgsForCall(0)
+				Expect(name).To(Equal(apiPipelines[0]))
+				Expect(pipelineMetadata(response)[0].Status).To(Equal("succeeded"))
+			})
+		})
+	})
+
+	Context("when the state of pipelines is declared", func() {
+		var (
+			paused    bool
+			notPaused bool
+			public    bool
+			notPublic bool
+		)
+
+		BeforeEach(func() {
+			paused, notPaused, public, notPublic = true, false, true, false
+
+			fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+				{Name: apiPipelines[0], Paused: false, Public: true},
+				{Name: apiPipelines[1], Paused: true, Public: false},
+			}, nil)
+
+			outRequest.Params.Pipelines[0].Paused = &paused
+			outRequest.Params.Pipelines[0].Public = &notPublic
+			outRequest.Params.Pipelines[1].Paused = &notPaused
+			outRequest.Params.Pipelines[1].Public = &public
+			outRequest.Params.Pipelines[1].Unpaused = false
+			outRequest.Params.Pipelines[1].Exposed = false
+		})
+
+		It("pauses and hides, or unpauses and exposes, each pipeline", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.PausePipelineCallCount()).To(Equal(1))
+			_, name := fakeFlyCommand.PausePipelineArgsForCall(0)
+			Expect(name).To(Equal(apiPipelines[0]))
+
+			Expect(fakeFlyCommand.HidePipelineCallCount()).To(Equal(1))
+			_, name = fakeFlyCommand.HidePipelineArgsForCall(0)
+			Expect(name).To(Equal(apiPipelines[0]))
+
+			Expect(fakeFlyCommand.UnpausePipelineCallCount()).To(Equal(1))
+			_, name = fakeFlyCommand.UnpausePipelineArgsForCall(0)
+			Expect(name).To(Equal(apiPipelines[1]))
+
+			Expect(fakeFlyCommand.ExposePipelineCallCount()).To(Equal(1))
+			_, name = fakeFlyCommand.ExposePipelineArgsForCall(0)
+			Expect(name).To(Equal(apiPipelines[1]))
+		})
+
+		Context("when the pipelines are already in that state", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+					{Name: apiPipelines[0], Paused: true, Public: false},
+					{Name: apiPipelines[1], Paused: false, Public: true},
+				}, nil)
+			})
+
+			It("leaves them as they are", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.PausePipelineCallCount()).To(Equal(0))
+				Expect(fakeFlyCommand.HidePipelineCallCount()).To(Equal(0))
+				Expect(fakeFlyCommand.UnpausePipelineCallCount()).To(Equal(0))
+				Expect(fakeFlyCommand.ExposePipelineCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when a pipeline is archived", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].Paused = nil
+				outRequest.Params.Pipelines[0].Public = nil
+				outRequest.Params.Pipelines[0].Archived = true
+			})
+
+			It("archives it instead of setting it", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.ArchivePipelineCallCount()).To(Equal(1))
+				_, name := fakeFlyCommand.ArchivePipelineArgsForCall(0)
+				Expect(name).To(Equal(apiPipelines[0]))
+
+				for i := 0; i < fakeFlyCommand.SetPipelineCallCount(); i++ {
+					_, name, _, _, _ := fakeFlyCommand.SetPipelineArgsForCall(i)
+					Expect(name).NotTo(Equal(apiPipelines[0]))
+				}
+
+				Expect(pipelineMetadata(response)[0].Status).To(Equal("archived"))
+				Expect(response.Version).NotTo(HaveKey(teamName + "/" + apiPipelines[0]))
+			})
+		})
+
+		Context("when a pipeline which is archived is not declared as archived", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+					{Name: apiPipelines[1], Paused: true, Archived: true},
+				}, nil)
+			})
+
+			It("sets it to unarchive it, then unpauses it", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(3))
+				Expect(fakeFlyCommand.ArchivePipelineCallCount()).To(Equal(0))
+				Expect(fakeFlyCommand.UnpausePipelineCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Context("when pipelines are instanced", func() {
+		var paused bool
+
+		BeforeEach(func() {
+			paused = true
+
+			outRequest.Params.Pipelines = []concourse.Pipeline{
+				{
+					Name:         "instanced",
+					InstanceVars: map[string]interface{}{"branch": "main"},
+					ConfigFile:   "pipeline_1.yml",
+					TeamName:     teamName,
+					Paused:       &paused,
+				},
+				{
+					Name:         "instanced",
+					InstanceVars: map[string]interface{}{"branch": "feature"},
+					ConfigFile:   "pipeline_1.yml",
+					TeamName:     teamName,
+					Paused:       &paused,
+					DependsOn:    []string{"instanced/branch:main"},
+				},
+			}
+
+			fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+				{Name: "instanced", InstanceVars: map[string]interface{}{"branch": "main"}, Paused: false},
+			}, nil)
+			fakeFlyCommand.GetPipelineReturns([]byte(pipelineContents[0]), nil)
+		})
+
+		It("sets and pauses each instance by its instance vars", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(2))
+			_, name, _, _, _ := fakeFlyCommand.SetPipelineArgsForCall(0)
+			Expect(name).To(Equal("instanced/branch:main"))
+			_, name, _, _, _ = fakeFlyCommand.SetPipelineArgsForCall(1)
+			Expect(name).To(Equal("instanced/branch:feature"))
+
+			// The new instance is already paused.
+			Expect(fakeFlyCommand.PausePipelineCallCount()).To(Equal(1))
+			_, name = fakeFlyCommand.PausePipelineArgsForCall(0)
+			Expect(name).To(Equal("instanced/branch:main"))
+
+			Expect(response.Version).To(HaveKey(teamName + "/instanced/branch:main"))
+			Expect(response.Version).To(HaveKey(teamName + "/instanced/branch:feature"))
+
+			metadata := pipelineMetadata(response)
+			Expect(metadata[0].InstanceVars).To(Equal(map[string]interface{}{"branch": "main"}))
+			Expect(metadata[0].PreviousVersion).NotTo(BeEmpty())
+			Expect(metadata[0].URL).To(Equal("some target/teams/main/pipelines/instanced?vars=%7B%22branch%22%3A%22main%22%7D"))
+			Expect(metadata[1].PreviousVersion).To(BeEmpty())
+		})
+
+		It("identifies each instance in the summary", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stderr.String()).To(MatchRegexp(`instanced/branch:main\s+%s\s+succeeded`, teamName))
+			Expect(stderr.String()).To(MatchRegexp(`instanced/branch:feature\s+%s\s+succeeded`, teamName))
+		})
+
+		Context("when prune is requested", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune = []concourse.Prune{
+					{
+						TeamName: teamName,
+						Keep:     []string{"kept"},
+					},
+				}
+
+				fakeFlyCommand.PipelinesReturns([]string{
+					"instanced/branch:main",
+					"instanced/branch:old",
+					"kept/branch:main",
+				}, nil)
+			})
+
+			It("prunes the undeclared instances, keeping those whose name matches", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(1))
+				_, name := fakeFlyCommand.DestroyPipelineArgsForCall(0)
+				Expect(name).To(Equal("instanced/branch:old"))
+			})
+		})
+	})
+
+	Context("when a pipeline is renamed", func() {
+		BeforeEach(func() {
+			outRequest.Params.Pipelines[0].RenameFrom = "old-pipeline"
+
+			fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+				{Name: "old-pipeline"},
+			}, nil)
+			fakeFlyCommand.GetPipelineReturns([]byte(pipelineContents[0]), nil)
+		})
+
+		It("renames the existing pipeline before setting it", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.RenamePipelineCallCount()).To(Equal(1))
+			team, oldName, newName := fakeFlyCommand.RenamePipelineArgsForCall(0)
+			Expect(team).To(Equal(teamName))
+			Expect(oldName).To(Equal("old-pipeline"))
+			Expect(newName).To(Equal(apiPipelines[0]))
+
+			Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(3))
+			Expect(pipelineMetadata(response)[0].PreviousVersion).NotTo(BeEmpty())
+			Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-1' (team 'main') renamed from 'old-pipeline'"))
+		})
+
+		Context("when it has already been renamed", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+					{Name: apiPipelines[0]},
+				}, nil)
+			})
+
+			It("does not rename it", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.RenamePipelineCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when both pipelines exist", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+					{Name: "old-pipeline"},
+					{Name: apiPipelines[0]},
+				}, nil)
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).To(MatchError("cannot rename pipeline 'old-pipeline' to 'pipeline-1' as both exist"))
+
+				Expect(fakeFlyCommand.RenamePipelineCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Context("when pipelines already exist", func() {
+		var getCounts map[string]int
+
+		BeforeEach(func() {
+			fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+				{Name: apiPipelines[0]},
+				{Name: apiPipelines[1]},
+			}, nil)
+
+			getCounts = map[string]int{}
+			fakeFlyCommand.GetPipelineStub = func(teamName string, name string) ([]byte, error) {
+				getCounts[name]++
+
+				// The first pipeline changes when it is set
+				if name == apiPipelines[0] && getCounts[name] == 1 {
+					return []byte("old config"), nil
+				}
+				return []byte(name), nil
+			}
+		})
+
+		It("returns the previous version of each pipeline", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			metadata := pipelineMetadata(response)
+
+			Expect(metadata[0].PreviousVersion).To(Equal(fmt.Sprintf("%x", md5.Sum([]byte("old config")))))
+			Expect(metadata[0].Version).NotTo(Equal(metadata[0].PreviousVersion))
+			Expect(metadata[0].Changed).To(BeTrue())
+
+			Expect(metadata[1].PreviousVersion).To(Equal(metadata[1].Version))
+			Expect(metadata[1].Changed).To(BeFalse())
+
+			Expect(metadata[2].PreviousVersion).To(BeEmpty())
+			Expect(metadata[2].Changed).To(BeTrue())
+		})
+	})
+
+	It("prints a summary of each pipeline", func() {
+		_, err := command.Run(outRequest)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(stderr.String()).To(MatchRegexp(`PIPELINE\s+TEAM\s+STATUS\s+ERROR\n`))
+		for _, p := range pipelines {
+			Expect(stderr.String()).To(MatchRegexp(`%s\s+%s\s+succeeded\s*\n`, p.Name, p.TeamName))
+		}
+	})
+
+	Context("when prune is requested", func() {
+		BeforeEach(func() {
+			outRequest.Params.Prune = []concourse.Prune{
+				{
+					TeamName: teamName,
+					Keep:     []string{"keep-*"},
+				},
+			}
+
+			fakeFlyCommand.PipelinesReturns([]string{
+				apiPipelines[0],
+				apiPipelines[1],
+				"orphan",
+				"keep-me",
+			}, nil)
+		})
+
+		It("destroys the undeclared pipelines of the team", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(1))
+			team, name := fakeFlyCommand.DestroyPipelineArgsForCall(0)
+			Expect(team).To(Equal(teamName))
+			Expect(name).To(Equal("orphan"))
+		})
+
+		Context("when a keep pattern is delimited by slashes", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune[0].Keep = []string{"/^keep-.*$/"}
+			})
+
+			It("matches it as a glob rather than a regular expression", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(2))
+				_, name := fakeFlyCommand.DestroyPipelineArgsForCall(1)
+				Expect(name).To(Equal("keep-me"))
+			})
+		})
+
+		Context("when the action is pause", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune[0].Action = concourse.PruneActionPause
+			})
+
+			It("pauses the undeclared pipelines instead", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(0))
+				Expect(fakeFlyCommand.PausePipelineCallCount()).To(Equal(1))
+				team, name := fakeFlyCommand.PausePipelineArgsForCall(0)
+				Expect(team).To(Equal(teamName))
+				Expect(name).To(Equal("orphan"))
+			})
+		})
+
+		Context("when the action is archive", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune[0].Action = concourse.PruneActionArchive
+			})
+
+			It("archives the undeclared pipelines instead", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(0))
+				Expect(fakeFlyCommand.ArchivePipelineCallCount()).To(Equal(1))
+				team, name := fakeFlyCommand.ArchivePipelineArgsForCall(0)
+				Expect(team).To(Equal(teamName))
+				Expect(name).To(Equal("orphan"))
+			})
+		})
+
+		Context("when dry run is also requested", func() {
+			BeforeEach(func() {
+				outRequest.Params.DryRun = true
+
+				for _, p := range pipelines {
+					err := ioutil.WriteFile(filepath.Join(sourcesDir, p.ConfigFile), []byte("jobs: []\n"), os.ModePerm)
+					Expect(err).NotTo(HaveOccurred())
+				}
+				outRequest.Params.Pipelines[0].VarsFiles = nil
+			})
+
+			It("does not prune any pipelines", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when destroying a pipeline returns an error", func() {
+			var (
+				expectedErr error
+			)
+
+			BeforeEach(func() {
+				expectedErr = fmt.Errorf("some error")
+				fakeFlyCommand.DestroyPipelineReturns(nil, expectedErr)
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).To(Equal(expectedErr))
+			})
+		})
+	})
+
+	Context("when dry run is requested", func() {
+		BeforeEach(func() {
+			outRequest.Params.DryRun = true
+
+			files := map[string]string{
+				"pipeline_1.yml": "jobs:\n- name: ((job-name))\n",
+				"pipeline_2.yml": pipelineContents[1],
+				"pipeline_3.yml": "jobs:\n- name: some-job\n",
+				"vars_1.yml":     "job-name: some-job\n",
+				"vars_2.yml":     "other: value\n",
+			}
+			for name, contents := range files {
+				err := ioutil.WriteFile(filepath.Join(sourcesDir, name), []byte(contents), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+				{Name: apiPipelines[0]},
+				{Name: apiPipelines[1], Paused: true},
+			}, nil)
+		})
+
+		It("does not modify any pipelines", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(0))
+			Expect(fakeFlyCommand.ExposePipelineCallCount()).To(Equal(0))
+			Expect(fakeFlyCommand.UnpausePipelineCallCount()).To(Equal(0))
+			Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(0))
+		})
+
+		It("lists the pipelines of each team once", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.PipelineStatesCallCount()).To(Equal(2))
+			Expect(fakeFlyCommand.PipelineStatesArgsForCall(0)).To(Equal(teamName))
+			Expect(fakeFlyCommand.PipelineStatesArgsForCall(1)).To(Equal(otherTeamName))
+		})
+
+		It("only gets pipelines which exist", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFlyCommand.GetPipelineCallCount()).To(Equal(2))
+			_, name := fakeFlyCommand.GetPipelineArgsForCall(0)
+			Expect(name).To(Equal(apiPipelines[0]))
+			_, name = fakeFlyCommand.GetPipelineArgsForCall(1)
+			Expect(name).To(Equal(apiPipelines[1]))
+		})
+
+		It("returns the diff of each pipeline as metadata", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Metadata).To(HaveLen(3))
+
+			Expect(response.Metadata[0].Name).To(Equal(teamName + "/" + apiPipelines[0]))
+			Expect(response.Metadata[0].Value).To(ContainSubstring(`"jobs":{"added":["some-job"]}`))
+			Expect(response.Metadata[0].Value).To(ContainSubstring(`"other":{"removed":["pipeline1"]}`))
+
+			Expect(response.Metadata[1].Name).To(Equal(teamName + "/" + apiPipelines[1]))
+			Expect(response.Metadata[1].Value).NotTo(ContainSubstring("added"))
+			Expect(response.Metadata[1].Value).NotTo(ContainSubstring("changed"))
+
+			Expect(response.Metadata[2].Name).To(Equal(otherTeamName + "/" + apiPipelines[2]))
+			Expect(response.Metadata[2].Value).To(ContainSubstring(`"jobs":{"added":["some-job"]}`))
+		})
+
+		It("returns the version of the existing pipelines", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(response.Version).To(HaveLen(2))
+			Expect(response.Version[teamName+"/"+apiPipelines[0]]).To(Equal("4f4bd60b18bf697cc68dac9cb95537c2"))
+		})
+
+		Context("when a config file does not exist", func() {
+			BeforeEach(func() {
+				err := os.Remove(filepath.Join(sourcesDir, "pipeline_3.yml"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns an error", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a pipeline is rendered", func() {
+			BeforeEach(func() {
+				err := ioutil.WriteFile(
+					filepath.Join(sourcesDir, "pipeline_3.yml"),
+					[]byte("jobs:\n- name: {{.job}}\n"),
+					os.ModePerm,
+				)
+				Expect(err).NotTo(HaveOccurred())
+
+				pipelines[2].Render = &concourse.Render{
+					Engine: concourse.RenderEngineTemplate,
+					Data:   map[string]interface{}{"job": "rendered-job"},
+				}
+				outRequest.Params.Pipelines = pipelines
+			})
+
+			It("diffs and reports the rendered config", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response.Metadata[2].Value).To(ContainSubstring(`"jobs":{"added":["rendered-job"]}`))
+				Expect(stderr.String()).To(ContainSubstring("rendered config:\n\njobs:\n- name: rendered-job\n"))
+			})
+		})
+
+		Context("when a pipeline is renamed", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].Name = "new-pipeline"
+				outRequest.Params.Pipelines[0].RenameFrom = apiPipelines[0]
+			})
+
+			It("diffs against the pipeline it is renamed from", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, name := fakeFlyCommand.GetPipelineArgsForCall(0)
+				Expect(name).To(Equal(apiPipelines[0]))
+
+				Expect(response.Metadata[0].Name).To(Equal(teamName + "/new-pipeline"))
+				Expect(response.Metadata[0].Value).To(ContainSubstring(`"renamed_from":"` + apiPipelines[0] + `"`))
+				Expect(response.Metadata[0].Value).To(ContainSubstring(`"other":{"removed":["pipeline1"]}`))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'new-pipeline' (team 'main') would be renamed from 'pipeline-1'"))
+			})
+
+			Context("when the new pipeline also exists", func() {
+				BeforeEach(func() {
+					fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+						{Name: apiPipelines[0]},
+						{Name: "new-pipeline"},
+					}, nil)
+				})
+
+				It("returns an error", func() {
+					_, err := command.Run(outRequest)
+					Expect(err).To(MatchError("cannot rename pipeline 'pipeline-1' to 'new-pipeline' as both exist"))
+				})
+			})
+		})
+
+		Context("when a pipeline is archived", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].Archived = true
+				outRequest.Params.Pipelines[2].Archived = true
+			})
+
+			It("reports the archive without a diff", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response.Metadata[0].Value).To(ContainSubstring(`"archived":true`))
+				Expect(response.Metadata[0].Value).NotTo(ContainSubstring("removed"))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-1' (team 'main') would be archived"))
+
+				Expect(response.Metadata[2].Value).NotTo(ContainSubstring("archived"))
+				Expect(response.Metadata[2].Value).NotTo(ContainSubstring("added"))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-3' (team 'some-other-team') does not exist; would not be archived"))
+			})
+		})
+
+		Context("when an archived pipeline is not declared archived", func() {
+			BeforeEach(func() {
+				fakeFlyCommand.PipelineStatesReturns([]fly.PipelineState{
+					{Name: apiPipelines[0], Archived: true},
+				}, nil)
+				outRequest.Params.Pipelines[0].Unpaused = true
+			})
+
+			It("reports that it would be unarchived, paused", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response.Metadata[0].Value).To(ContainSubstring(`"unarchived":true`))
+				Expect(response.Metadata[0].Value).To(ContainSubstring(`"paused":false`))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-1' (team 'main') would be unarchived"))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-1' (team 'main') would be unpaused"))
+			})
+		})
+
+		Context("when pipelines would be paused or exposed", func() {
+			BeforeEach(func() {
+				paused := true
+				notPaused := false
+				public := true
+
+				outRequest.Params.Pipelines[0].Paused = &paused
+				outRequest.Params.Pipelines[0].Public = &public
+				outRequest.Params.Pipelines[1].Paused = &notPaused
+			})
+
+			It("reports the state changes", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response.Metadata[0].Value).To(ContainSubstring(`"paused":true,"public":true`))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-1' (team 'main') would be paused"))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-1' (team 'main') would be exposed"))
+
+				Expect(response.Metadata[1].Value).To(ContainSubstring(`"paused":false`))
+				Expect(stderr.String()).To(ContainSubstring("pipeline 'pipeline-2' (team 'main') would be unpaused"))
+			})
+
+			It("does not report state which would not change", func() {
+				outRequest.Params.Pipelines[1].Public = new(bool)
+
+				response, err := command.Run(outRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(response.Metadata[1].Value).NotTo(ContainSubstring("public"))
+				Expect(stderr.String()).NotTo(ContainSubstring("pipeline 'pipeline-2' (team 'main') would be hidden"))
+			})
+		})
+	})
+
+	Context("when a pipeline is rendered", func() {
+		var (
+			renderedConfig     string
+			renderedConfigPath string
+		)
+
+		BeforeEach(func() {
+			err := ioutil.WriteFile(
+				filepath.Join(sourcesDir, pipelines[1].ConfigFile),
+				[]byte("jobs:\n- name: {{.job}}\n"),
+				os.ModePerm,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			pipelines[1].Render = &concourse.Render{
+				Engine: concourse.RenderEngineTemplate,
+				Data:   map[string]interface{}{"job": "some-job"},
+			}
+			outRequest.Params.Pipelines = pipelines
+		})
+
+		JustBeforeEach(func() {
+			fakeFlyCommand.SetPipelineStub = func(teamName, pipelineName, configFilepath string, varsFilepaths []string, vars map[string]interface{}) ([]byte, error) {
+				if pipelineName == pipelines[1].Name {
+					renderedConfigPath = configFilepath
+
+					b, err := ioutil.ReadFile(configFilepath)
+					Expect(err).NotTo(HaveOccurred())
+					renderedConfig = string(b)
+				}
+				return nil, nil
+			}
+		})
+
+		It("sets the rendered config and removes it afterwards", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(renderedConfigPath).NotTo(Equal(filepath.Join(sourcesDir, pipelines[1].ConfigFile)))
+			Expect(renderedConfig).To(Equal("jobs:\n- name: some-job\n"))
+
+			_, err = os.Stat(renderedConfigPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		Context("when rendering fails", func() {
+			BeforeEach(func() {
+				pipelines[1].Render.Data = nil
+			})
+
+			It("returns an error without setting the pipeline", func() {
+				_, err := command.Run(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Context("when pipelines have secrets", func() {
+		BeforeEach(func() {
+			outRequest.Source.SensitiveVars = []string{"*-key", "*.token", "branch"}
+
+			pipelines[0].Vars = map[string]interface{}{
+				"deploy-key": "some-deploy-key",
+				"short-key":  "x9q",
+				"tls-key":    "-----BEGIN KEY-----\nsome-tls-key\n-----END KEY-----\n",
+			}
+			pipelines[2].Vars["db"] = map[string]interface{}{
+				"password": "some-db-password",
+			}
+			pipelines[2].Vars["pin"] = "4821"
+			pipelines[2].SensitiveVars = []string{"db.password", "pin"}
+			outRequest.Params.Pipelines = pipelines
+
+			err := ioutil.WriteFile(
+				filepath.Join(sourcesDir, pipelines[0].VarsFiles[0]),
+				[]byte("github:\n  token: some-github-token\n  org: some-github-org\nbranch: main\nreplicas: 3\n"),
+				os.ModePerm,
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			fakeFlyCommand.SetPipelineStub = func(teamName, pipelineName, configFilepath string, varsFilepaths []string, vars map[string]interface{}) ([]byte, error) {
+				output := "token: some-github-token, password: some-db-password, org: some-github-org, branch: main\n"
+				for key, value := range vars {
+					// As fly is given vars as arguments
+					encoded, err := json.Marshal(value)
+					Expect(err).NotTo(HaveOccurred())
+
+					output += fmt.Sprintf("-y %s=%s\n", key, encoded)
+				}
+				return []byte(output), nil
+			}
+		})
+
+		It("redacts sensitive vars from the log", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logContents.String()).To(ContainSubstring("***REDACTED-VAR-deploy-key***"))
+			Expect(logContents.String()).To(ContainSubstring("***REDACTED-VAR-db.password***"))
+			Expect(logContents.String()).NotTo(ContainSubstring("some-deploy-key"))
+			Expect(logContents.String()).NotTo(ContainSubstring("some-db-password"))
+		})
+
+		It("redacts vars file values from the log and stderr", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logContents.String()).NotTo(ContainSubstring("some-github-token"))
+			Expect(stderr.String()).NotTo(ContainSubstring("some-github-token"))
+			Expect(stderr.String()).To(ContainSubstring("token: ***REDACTED-VAR-github.token***"))
+			Expect(stderr.String()).To(ContainSubstring("password: ***REDACTED-VAR-db.password***"))
+		})
+
+		It("redacts multi-line values as they are passed to fly", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stderr.String()).NotTo(ContainSubstring("some-tls-key"))
+			Expect(logContents.String()).NotTo(ContainSubstring("some-tls-key"))
+			Expect(stderr.String()).To(ContainSubstring(`-y tls-key="***REDACTED-VAR-tls-key***"`))
+		})
+
+		It("redacts short values of vars the pipeline marks as sensitive", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stderr.String()).NotTo(ContainSubstring("4821"))
+			Expect(logContents.String()).NotTo(ContainSubstring("4821"))
+		})
+
+		It("redacts team passwords", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logContents.String()).NotTo(ContainSubstring(password))
+		})
+
+		It("does not redact vars which are not sensitive", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logContents.String()).To(ContainSubstring("launch-missiles:true"))
+		})
+
+		It("does not redact vars file values which are not sensitive", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stderr.String()).To(ContainSubstring("org: some-github-org"))
+		})
+
+		It("does not redact sensitive values which are too short to be secrets", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logContents.String()).To(ContainSubstring("short-key:x9q"))
+			Expect(stderr.String()).To(ContainSubstring("branch: main"))
+		})
 	})
 
 	Context("when insecure parses as true", func() {
@@ -232,8 +1346,8 @@ pipeline3: foo
 			_, err := command.Run(outRequest)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(5))
-			_, _, _, _, insecure := fakeFlyCommand.LoginArgsForCall(0)
+			Expect(fakeFlyCommand.LoginCallCount()).To(Equal(2))
+			_, _, _, insecure := fakeFlyCommand.LoginArgsForCall(0)
 
 			Expect(insecure).To(BeTrue())
 		})
@@ -286,6 +1400,98 @@ pipeline3: foo
 
 			Expect(err).To(Equal(setPipelinesErr))
 		})
+
+		It("does not set any further pipelines", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(1))
+		})
+
+		It("reports the remaining pipelines as skipped", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			metadata := pipelineMetadata(response)
+			Expect(metadata).To(HaveLen(3))
+
+			Expect(metadata[0].Status).To(Equal("failed"))
+			Expect(metadata[0].Error).To(Equal("some error"))
+			Expect(metadata[0].Changed).To(BeFalse())
+
+			for _, m := range metadata[1:] {
+				Expect(m.Status).To(Equal("skipped"))
+				Expect(m.Error).To(Equal("an earlier pipeline failed"))
+			}
+		})
+	})
+
+	Context("when fail fast is false", func() {
+		BeforeEach(func() {
+			failFast := false
+			outRequest.Params.FailFast = &failFast
+		})
+
+		JustBeforeEach(func() {
+			fakeFlyCommand.SetPipelineStub = func(teamName string, name string, _ string, _ []string, _ map[string]interface{}) ([]byte, error) {
+				if name == apiPipelines[0] {
+					return []byte("some output\nerror: bad config"), fmt.Errorf("some error\nexit status 1")
+				}
+				return nil, nil
+			}
+		})
+
+		It("attempts every pipeline", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(len(pipelines)))
+		})
+
+		It("returns an error with the number of failures", func() {
+			_, err := command.Run(outRequest)
+			Expect(err).To(MatchError("1 of 3 pipelines failed to be set"))
+		})
+
+		It("reports the result of each pipeline", func() {
+			response, err := command.Run(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			metadata := pipelineMetadata(response)
+			Expect(metadata).To(HaveLen(3))
+
+			Expect(metadata[0].Status).To(Equal("failed"))
+			Expect(metadata[0].Error).To(Equal("some error exit status 1"))
+			Expect(metadata[1].Status).To(Equal("succeeded"))
+			Expect(metadata[2].Status).To(Equal("succeeded"))
+
+			Expect(stderr.String()).To(MatchRegexp(`%s\s+%s\s+failed\s+some error exit status 1\n`, apiPipelines[0], teamName))
+			Expect(stderr.String()).To(MatchRegexp(`%s\s+%s\s+succeeded\s*\n`, apiPipelines[1], teamName))
+		})
+
+		It("does not prune pipelines", func() {
+			outRequest.Params.Prune = []concourse.Prune{{TeamName: teamName}}
+
+			_, err := command.Run(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(fakeFlyCommand.DestroyPipelineCallCount()).To(Equal(0))
+		})
+
+		Context("when a pipeline depends on one which failed", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[1].DependsOn = []string{apiPipelines[0]}
+			})
+
+			It("skips it", func() {
+				response, err := command.Run(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(fakeFlyCommand.SetPipelineCallCount()).To(Equal(2))
+				Expect(pipelineMetadata(response)[1].Status).To(Equal("skipped"))
+				Expect(pipelineMetadata(response)[1].Error).To(Equal("dependency pipeline-1 failed"))
+			})
+		})
 	})
 
 	Context("when getting pipeline returns an error", func() {
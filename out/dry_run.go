@@ -0,0 +1,224 @@
+package out
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/diff"
+	"github.com/concourse/concourse-pipeline-resource/fly"
+	"github.com/concourse/concourse-pipeline-resource/render"
+)
+
+// dryRunChanges are the changes which applying a pipeline would make: its
+// config diff, and how it would be renamed, archived, paused or exposed.
+type dryRunChanges struct {
+	diff.Diff
+
+	RenamedFrom string `json:"renamed_from,omitempty"`
+	Archived    bool   `json:"archived,omitempty"`
+	Unarchived  bool   `json:"unarchived,omitempty"`
+
+	// Paused and Public are the state the pipeline would be put into, if it
+	// would change.
+	Paused *bool `json:"paused,omitempty"`
+	Public *bool `json:"public,omitempty"`
+}
+
+// dryRun reports the changes that applying each pipeline would make without
+// making them. The returned version describes the pipelines as they
+// currently exist.
+func (c *Command) dryRun(
+	input concourse.OutRequest,
+	teams map[string]concourse.Team,
+	insecure bool,
+) (concourse.OutResponse, error) {
+	logins := c.newLogins(input.Source.Target, teams, insecure)
+
+	pipelineVersions := make(map[string]string)
+	metadata := []concourse.Metadata{}
+
+	c.logger.Debugf("Performing dry run\n")
+
+	// The state of the pipelines which exist in each team, listed once per
+	// team.
+	existingPipelines := make(map[string]map[string]fly.PipelineState)
+
+	for _, p := range input.Params.Pipelines {
+		if _, found := teams[p.TeamName]; !found {
+			return concourse.OutResponse{}, fmt.Errorf("team (%s) configuration not found for pipeline (%s)", p.TeamName, p.Name)
+		}
+
+		if _, found := existingPipelines[p.TeamName]; found {
+			continue
+		}
+
+		err := logins.login(p.TeamName)
+		if err != nil {
+			return concourse.OutResponse{}, err
+		}
+
+		states, err := c.flyCommand.PipelineStates(p.TeamName)
+		if err != nil {
+			return concourse.OutResponse{}, err
+		}
+
+		existingPipelines[p.TeamName] = make(map[string]fly.PipelineState)
+		for _, state := range states {
+			existingPipelines[p.TeamName][state.Ref()] = state
+		}
+	}
+
+	for _, p := range input.Params.Pipelines {
+		changes, err := c.dryRunPipeline(p, existingPipelines[p.TeamName], pipelineVersions)
+		if err != nil {
+			return concourse.OutResponse{}, err
+		}
+
+		changesJSON, err := json.Marshal(changes)
+		if err != nil {
+			// Untested as the changes always marshal to JSON
+			return concourse.OutResponse{}, err
+		}
+
+		metadata = append(metadata, concourse.Metadata{
+			Name:  concourse.VersionKey(p.TeamName, p.Ref().String()),
+			Value: string(changesJSON),
+		})
+	}
+
+	err := c.prune(input, logins, true)
+	if err != nil {
+		return concourse.OutResponse{}, err
+	}
+	c.logger.Debugf("Dry run complete\n")
+
+	response := concourse.OutResponse{
+		Version:  pipelineVersions,
+		Metadata: metadata,
+	}
+
+	return response, nil
+}
+
+// dryRunPipeline reports the changes which applyPipeline would make to a
+// pipeline, given the state of the team's pipelines. The version of each
+// existing pipeline which is fetched is added to pipelineVersions.
+func (c *Command) dryRunPipeline(
+	p concourse.Pipeline,
+	existing map[string]fly.PipelineState,
+	pipelineVersions map[string]string,
+) (dryRunChanges, error) {
+	ref := p.Ref().String()
+	changes := dryRunChanges{}
+
+	currentRef := ref
+	state, exists := existing[ref]
+
+	if p.RenameFrom != "" {
+		old, oldExists := existing[p.RenameFrom]
+		if oldExists && exists {
+			return dryRunChanges{}, renameConflict(p.RenameFrom, ref)
+		}
+
+		if oldExists {
+			fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') would be renamed from '%s'\n", ref, p.TeamName, p.RenameFrom)
+			changes.RenamedFrom = p.RenameFrom
+			currentRef, state, exists = p.RenameFrom, old, true
+		}
+	}
+
+	var current []byte
+	if exists {
+		c.logger.Debugf("Getting pipeline: %s\n", currentRef)
+
+		var err error
+		current, err = c.flyCommand.GetPipeline(p.TeamName, currentRef)
+		if err != nil {
+			return dryRunChanges{}, err
+		}
+
+		pipelineVersions[concourse.VersionKey(p.TeamName, currentRef)] = fmt.Sprintf(
+			"%x",
+			md5.Sum(current),
+		)
+	}
+
+	if p.Archived {
+		switch {
+		case !exists:
+			fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') does not exist; would not be archived\n", ref, p.TeamName)
+		case state.Archived:
+			fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') is already archived\n", ref, p.TeamName)
+		default:
+			fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') would be archived\n", ref, p.TeamName)
+			changes.Archived = true
+		}
+
+		return changes, nil
+	}
+
+	if state.Archived {
+		// As in applyPipeline, setting the pipeline unarchives it, paused.
+		fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') would be unarchived\n", ref, p.TeamName)
+		changes.Unarchived = true
+		state.Paused = true
+	}
+
+	configFilepath, cleanup, err := c.configFilepath(p)
+	if err != nil {
+		return dryRunChanges{}, err
+	}
+
+	if p.Render != nil {
+		rendered, err := ioutil.ReadFile(configFilepath)
+		if err != nil {
+			cleanup()
+			return dryRunChanges{}, err
+		}
+
+		fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') rendered config:\n\n%s\n", ref, p.TeamName, rendered)
+	}
+
+	var varsFilepaths []string
+	for _, v := range p.VarsFiles {
+		varsFilepaths = append(varsFilepaths, filepath.Join(c.sourcesDir, v))
+	}
+
+	proposed, err := render.Config(configFilepath, varsFilepaths, p.Ref().Vars(p.Vars))
+	cleanup()
+	if err != nil {
+		return dryRunChanges{}, err
+	}
+
+	changes.Diff, err = diff.Configs(current, proposed)
+	if err != nil {
+		return dryRunChanges{}, err
+	}
+
+	c.logger.Debugf("pipeline '%s' dry run; changes:\n\n%s\n", ref, changes.Diff)
+	fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') dry run; changes:\n\n%s\n", ref, p.TeamName, changes.Diff)
+
+	changes.Paused, changes.Public = stateChanges(p, state, exists)
+
+	if changes.Paused != nil {
+		action := "unpaused"
+		if *changes.Paused {
+			action = "paused"
+		}
+		fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') would be %s\n", ref, p.TeamName, action)
+	}
+
+	if changes.Public != nil {
+		action := "hidden"
+		if *changes.Public {
+			action = "exposed"
+		}
+		fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') would be %s\n", ref, p.TeamName, action)
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,171 @@
+package out
+
+import (
+	"crypto/md5"
+	"fmt"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/fly"
+)
+
+// applyPipeline brings a pipeline of a team which has been logged in to into
+// its declared state: renaming it, setting or archiving it, then pausing and
+// exposing it as declared. existing is the state of the team's pipelines
+// before any were applied. It may be called concurrently.
+//
+// The version of the pipeline's config before it was applied is returned if
+// it existed.
+func (c *Command) applyPipeline(
+	p concourse.Pipeline,
+	existing map[string]fly.PipelineState,
+) (status string, previousVersion string, err error) {
+	ref := p.Ref().String()
+	state, exists := existing[ref]
+
+	if p.RenameFrom != "" {
+		old, oldExists := existing[p.RenameFrom]
+		if oldExists && exists {
+			return "", "", renameConflict(p.RenameFrom, ref)
+		}
+
+		if oldExists {
+			output, err := c.flyCommand.RenamePipeline(p.TeamName, p.RenameFrom, ref)
+			c.logger.Debugf("pipeline '%s' renamed to '%s'; output:\n\n%s\n", p.RenameFrom, ref, string(output))
+			if err != nil {
+				return "", "", err
+			}
+			fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') renamed from '%s'\n", ref, p.TeamName, p.RenameFrom)
+
+			state, exists = old, true
+		}
+	}
+
+	if p.Archived {
+		return c.archivePipeline(p, state, exists)
+	}
+
+	var current []byte
+	if exists {
+		c.logger.Debugf("Getting pipeline: %s\n", ref)
+		current, err = c.flyCommand.GetPipeline(p.TeamName, ref)
+		if err != nil {
+			return "", "", err
+		}
+
+		previousVersion = fmt.Sprintf("%x", md5.Sum(current))
+	}
+
+	if state.Archived {
+		// Setting an archived pipeline is the only way to unarchive it, so
+		// it is set even if its config is unchanged. It is paused once it
+		// has been unarchived.
+		current = nil
+		state.Paused = true
+	}
+
+	status, err = c.setPipeline(p, current)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = c.applyState(p, state, exists)
+	if err != nil {
+		return "", "", err
+	}
+
+	return status, previousVersion, nil
+}
+
+// renameConflict is the error when a pipeline can not be renamed as a
+// pipeline with the new name exists.
+func renameConflict(from string, to string) error {
+	return fmt.Errorf("cannot rename pipeline '%s' to '%s' as both exist", from, to)
+}
+
+func (c *Command) archivePipeline(
+	p concourse.Pipeline,
+	state fly.PipelineState,
+	exists bool,
+) (string, string, error) {
+	ref := p.Ref().String()
+
+	switch {
+	case !exists:
+		fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') does not exist; not archived\n", ref, p.TeamName)
+	case state.Archived:
+		c.logger.Debugf("pipeline '%s' already archived\n", ref)
+	default:
+		output, err := c.flyCommand.ArchivePipeline(p.TeamName, ref)
+		c.logger.Debugf("pipeline '%s' archived; output:\n\n%s\n", ref, string(output))
+		if err != nil {
+			return "", "", err
+		}
+		fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') archived\n", ref, p.TeamName)
+	}
+
+	return statusArchived, "", nil
+}
+
+// applyState pauses or unpauses, and exposes or hides, a pipeline which has
+// been set, unless it is already in the declared state.
+func (c *Command) applyState(p concourse.Pipeline, state fly.PipelineState, exists bool) error {
+	ref := p.Ref().String()
+	paused, public := stateChanges(p, state, exists)
+
+	if paused != nil {
+		var err error
+		if *paused {
+			_, err = c.flyCommand.PausePipeline(p.TeamName, ref)
+		} else {
+			_, err = c.flyCommand.UnpausePipeline(p.TeamName, ref)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if public != nil {
+		var err error
+		if *public {
+			_, err = c.flyCommand.ExposePipeline(p.TeamName, ref)
+		} else {
+			_, err = c.flyCommand.HidePipeline(p.TeamName, ref)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stateChanges returns whether a pipeline must be paused and whether it must
+// be exposed once it has been set, or nil if it is already in the declared
+// state. A pipeline which did not exist is paused and hidden once it has
+// been set.
+func stateChanges(p concourse.Pipeline, state fly.PipelineState, exists bool) (paused *bool, public *bool) {
+	if !exists {
+		state = fly.PipelineState{Name: p.Name, InstanceVars: p.InstanceVars, Paused: true}
+	}
+
+	paused = p.Paused
+	if paused == nil && p.Unpaused {
+		paused = new(bool)
+	}
+
+	if paused != nil && *paused == state.Paused {
+		paused = nil
+	}
+
+	public = p.Public
+	if public == nil && p.Exposed {
+		exposed := true
+		public = &exposed
+	}
+
+	if public != nil && *public == state.Public {
+		public = nil
+	}
+
+	return paused, public
+}
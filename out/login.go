@@ -0,0 +1,56 @@
+package out
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/fly"
+)
+
+// logins logs in to each team at most once per run. It is not safe for
+// concurrent use; every team is logged in to before pipelines are set
+// concurrently.
+type logins struct {
+	command  *Command
+	target   string
+	teams    map[string]concourse.Team
+	insecure bool
+
+	loggedIn map[string]bool
+}
+
+func (c *Command) newLogins(
+	target string,
+	teams map[string]concourse.Team,
+	insecure bool,
+) *logins {
+	return &logins{
+		command:  c,
+		target:   target,
+		teams:    teams,
+		insecure: insecure,
+		loggedIn: make(map[string]bool),
+	}
+}
+
+// login logs in to the team, which must be configured in source, unless it
+// has already been logged in to.
+func (l *logins) login(teamName string) error {
+	if l.loggedIn[teamName] {
+		return nil
+	}
+
+	l.command.logger.Debugf("Performing login\n")
+	_, err := l.command.flyCommand.Login(
+		l.target,
+		teamName,
+		fly.TeamCredentials(l.teams[teamName]),
+		l.insecure,
+	)
+	if err != nil {
+		return err
+	}
+
+	l.command.logger.Debugf("Login successful\n")
+	l.loggedIn[teamName] = true
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package out
+
+import (
+	"fmt"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+// prune applies the prune action of each configured team to the pipelines of
+// that team which are not declared in params and not matched by keep. If
+// dryRun is true the orphaned pipelines are only reported.
+func (c *Command) prune(
+	input concourse.OutRequest,
+	logins *logins,
+	dryRun bool,
+) error {
+	for _, pr := range input.Params.Prune {
+		if _, found := logins.teams[pr.TeamName]; !found {
+			return fmt.Errorf("team (%s) configuration not found for prune", pr.TeamName)
+		}
+
+		err := logins.login(pr.TeamName)
+		if err != nil {
+			return err
+		}
+
+		existingPipelines, err := c.flyCommand.Pipelines(pr.TeamName)
+		if err != nil {
+			return err
+		}
+		c.logger.Debugf("Found pipelines (%s): %+v\n", pr.TeamName, existingPipelines)
+
+		declared := []string{}
+		for _, p := range input.Params.Pipelines {
+			if p.TeamName == pr.TeamName {
+				declared = append(declared, p.Ref().String())
+			}
+		}
+
+		for _, pipelineName := range existingPipelines {
+			if concourse.StringContains(declared, pipelineName) {
+				continue
+			}
+
+			// Keep patterns match the names of pipelines, so that they
+			// keep every instance of an instanced pipeline.
+			ref, err := concourse.ParsePipelineRef(pipelineName)
+			if err != nil {
+				return err
+			}
+
+			keep, err := concourse.MatchAnyGlob(pr.Keep, ref.Name)
+			if err != nil {
+				return err
+			}
+
+			if keep {
+				c.logger.Debugf("Keeping pipeline: %s\n", pipelineName)
+				continue
+			}
+
+			action := pr.Action
+			if action == "" {
+				action = concourse.PruneActionDestroy
+			}
+
+			if dryRun {
+				fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') would be pruned: %s\n", pipelineName, pr.TeamName, action)
+				continue
+			}
+
+			var pruneOutput []byte
+			switch action {
+			case concourse.PruneActionPause:
+				pruneOutput, err = c.flyCommand.PausePipeline(pr.TeamName, pipelineName)
+			case concourse.PruneActionArchive:
+				pruneOutput, err = c.flyCommand.ArchivePipeline(pr.TeamName, pipelineName)
+			default:
+				pruneOutput, err = c.flyCommand.DestroyPipeline(pr.TeamName, pipelineName)
+			}
+
+			c.logger.Debugf("pipeline '%s' pruned (%s); output:\n\n%s\n", pipelineName, action, string(pruneOutput))
+			fmt.Fprintf(c.stderr, "pipeline '%s' (team '%s') pruned: %s\n", pipelineName, pr.TeamName, action)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
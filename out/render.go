@@ -0,0 +1,45 @@
+package out
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/render"
+)
+
+// configFilepath returns the path of the config to set for p. If p has a
+// render step, the config is rendered to a temp file which cleanup removes.
+func (c *Command) configFilepath(p concourse.Pipeline) (path string, cleanup func(), err error) {
+	configFilepath := filepath.Join(c.sourcesDir, p.ConfigFile)
+
+	if p.Render == nil {
+		return configFilepath, func() {}, nil
+	}
+
+	c.logger.Debugf("Rendering pipeline config with %s: %s\n", p.Render.Engine, configFilepath)
+	rendered, err := render.Engine(configFilepath, *p.Render, c.sourcesDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	f, err := ioutil.TempFile("", "concourse-pipeline-resource-rendered-*.yml")
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	cleanup = func() {
+		os.Remove(f.Name())
+	}
+
+	_, err = f.Write(rendered)
+	if err != nil {
+		// Untested as it is too hard to force a write to a temp file to fail
+		cleanup()
+		return "", nil, err
+	}
+
+	return f.Name(), cleanup, nil
+}
@@ -0,0 +1,139 @@
+package out
+
+import (
+	"fmt"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+const (
+	statusSucceeded = "succeeded"
+	statusUnchanged = "unchanged"
+	statusArchived  = "archived"
+	statusFailed    = "failed"
+	statusSkipped   = "skipped"
+)
+
+// pipelineResult records what happened when setting a pipeline. Err is the
+// reason a pipeline failed or was skipped.
+type pipelineResult struct {
+	Pipeline concourse.Pipeline
+	Status   string
+	Err      error
+}
+
+type scheduleResult struct {
+	index  int
+	status string
+	err    error
+}
+
+// schedule calls set for each pipeline, running up to concurrency calls at
+// once; set returns the status of a pipeline which did not fail. A pipeline
+// is only started once every pipeline it depends on has been set, and
+// otherwise pipelines are started in the order they are declared.
+// Dependencies must be declared before their dependents.
+//
+// If failFast is true no more pipelines are started once set returns an
+// error. Otherwise every pipeline is attempted, except those depending on a
+// pipeline which failed or was skipped. The result of each pipeline is
+// returned, in declared order, once all those started have finished.
+func schedule(
+	pipelines []concourse.Pipeline,
+	concurrency int,
+	failFast bool,
+	set func(concourse.Pipeline) (string, error),
+) []pipelineResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	indexes := make(map[string]int, len(pipelines))
+	for i, p := range pipelines {
+		indexes[concourse.VersionKey(p.TeamName, p.Ref().String())] = i
+	}
+
+	results := make([]pipelineResult, len(pipelines))
+	started := make([]bool, len(pipelines))
+
+	// blocked returns whether a dependency of the pipeline has not yet
+	// succeeded, and the error if one never will.
+	blocked := func(i int) (bool, error) {
+		for j, key := range pipelines[i].DependencyKeys() {
+			d, found := indexes[key]
+			if !found {
+				continue
+			}
+
+			switch results[d].Status {
+			case statusSucceeded, statusUnchanged, statusArchived:
+			case "":
+				return true, nil
+			default:
+				return true, fmt.Errorf("dependency %s %s", pipelines[i].DependsOn[j], results[d].Status)
+			}
+		}
+		return false, nil
+	}
+
+	finished := make(chan scheduleResult)
+	running := 0
+	failed := false
+
+	for {
+		for i := range pipelines {
+			if (failFast && failed) || running >= concurrency {
+				break
+			}
+
+			if started[i] {
+				continue
+			}
+
+			isBlocked, err := blocked(i)
+			if err != nil {
+				started[i] = true
+				results[i] = pipelineResult{Pipeline: pipelines[i], Status: statusSkipped, Err: err}
+				continue
+			}
+
+			if isBlocked {
+				continue
+			}
+
+			started[i] = true
+			running++
+
+			go func(i int) {
+				status, err := set(pipelines[i])
+				finished <- scheduleResult{index: i, status: status, err: err}
+			}(i)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		r := <-finished
+		running--
+
+		results[r.index] = pipelineResult{Pipeline: pipelines[r.index], Status: r.status}
+		if r.err != nil {
+			results[r.index].Status = statusFailed
+			results[r.index].Err = r.err
+			failed = true
+		}
+	}
+
+	for i, r := range results {
+		if r.Status == "" {
+			results[i] = pipelineResult{
+				Pipeline: pipelines[i],
+				Status:   statusSkipped,
+				Err:      fmt.Errorf("an earlier pipeline failed"),
+			}
+		}
+	}
+
+	return results
+}
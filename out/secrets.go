@@ -0,0 +1,147 @@
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/render"
+)
+
+// minSensitiveValueLength is the length below which the values of vars
+// matching the source's sensitive patterns are not redacted, as such broad
+// patterns would otherwise redact values such as `main` or `1` wherever they
+// appear, e.g. within pipeline names. Vars matching the pipeline's patterns
+// are always redacted.
+const minSensitiveValueLength = 5
+
+// registerSecrets adds the values which must never be logged to the
+// command's secrets: the values of vars, including those read from vars
+// files, which are marked as sensitive, either by the pipeline or by a
+// source pattern.
+func (c *Command) registerSecrets(input concourse.OutRequest) error {
+	c.secrets.AddAll(concourse.SanitizedSource(input.Source))
+
+	for _, p := range input.Params.Pipelines {
+		for _, v := range p.VarsFiles {
+			varsFilepath := filepath.Join(c.sourcesDir, v)
+
+			fileVars, err := render.LoadVars([]string{varsFilepath}, nil)
+			if err != nil {
+				if os.IsNotExist(err) {
+					// fly reports missing vars files when setting the pipeline.
+					continue
+				}
+				return err
+			}
+
+			err = c.registerSensitiveVars(fileVars, input.Source.SensitiveVars, p.SensitiveVars)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := c.registerSensitiveVars(p.Vars, input.Source.SensitiveVars, p.SensitiveVars)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Command) registerSensitiveVars(
+	vars map[string]interface{},
+	sourcePatterns []string,
+	pipelinePatterns []string,
+) error {
+	for _, leaf := range flattenVars(vars) {
+		explicit, err := concourse.MatchAny(pipelinePatterns, leaf.path)
+		if err != nil {
+			return err
+		}
+
+		sensitive := explicit
+		if !sensitive {
+			sensitive, err = concourse.MatchAny(sourcePatterns, leaf.path)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !sensitive {
+			continue
+		}
+
+		switch value := leaf.value.(type) {
+		case nil, bool:
+		default:
+			secret := fmt.Sprintf("%v", value)
+			if !explicit && len(secret) < minSensitiveValueLength {
+				continue
+			}
+
+			replacement := fmt.Sprintf("***REDACTED-VAR-%s***", leaf.path)
+			c.secrets.Add(secret, replacement)
+
+			// Vars are passed to fly as JSON, whose arguments are logged, so
+			// the value is also redacted as it is encoded, with characters
+			// such as newlines and quotes escaped.
+			if str, ok := value.(string); ok {
+				encoded, err := json.Marshal(str)
+				if err != nil {
+					// Untested as a string always marshals to JSON
+					return err
+				}
+
+				c.secrets.Add(string(encoded[1:len(encoded)-1]), replacement)
+			}
+		}
+	}
+
+	return nil
+}
+
+type varLeaf struct {
+	path  string
+	value interface{}
+}
+
+// flattenVars returns each scalar value in vars with its dotted path, as
+// used to refer to nested vars in `((var.field))` syntax.
+func flattenVars(vars map[string]interface{}) []varLeaf {
+	var leaves []varLeaf
+
+	for _, k := range concourse.SortedKeys(vars) {
+		leaves = append(leaves, flattenValue(k, vars[k])...)
+	}
+
+	return leaves
+}
+
+func flattenValue(path string, value interface{}) []varLeaf {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var leaves []varLeaf
+		for _, leaf := range flattenVars(v) {
+			leaves = append(leaves, varLeaf{path: path + "." + leaf.path, value: leaf.value})
+		}
+		return leaves
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			converted[fmt.Sprintf("%v", k)] = nested
+		}
+		return flattenValue(path, converted)
+	case []interface{}:
+		var leaves []varLeaf
+		for i, nested := range v {
+			leaves = append(leaves, flattenValue(fmt.Sprintf("%s.%d", path, i), nested)...)
+		}
+		return leaves
+	default:
+		return []varLeaf{{path: path, value: value}}
+	}
+}
@@ -0,0 +1,100 @@
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+// summarize prints a table of the result of each pipeline to stderr. If any
+// pipeline failed an error is returned: the first failure if failFast is
+// true, as no further pipelines were attempted, and otherwise the number of
+// failures.
+func (c *Command) summarize(results []pipelineResult, failFast bool) error {
+	w := tabwriter.NewWriter(c.stderr, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "\nPIPELINE\tTEAM\tSTATUS\tERROR\n")
+
+	var firstErr error
+	var failures int
+
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Pipeline.Ref().String(), r.Pipeline.TeamName, r.Status, reason(r))
+
+		if r.Status == statusFailed {
+			failures++
+			if firstErr == nil {
+				firstErr = r.Err
+			}
+		}
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if failures == 0 {
+		return nil
+	}
+
+	if failFast {
+		return firstErr
+	}
+
+	return fmt.Errorf("%d of %d pipelines failed to be set", failures, len(results))
+}
+
+// pipelineMetadata returns a metadata entry for each pipeline, describing its
+// result and how its config changed. Versions are keyed by version key;
+// pipelines missing from previous did not exist before they were set.
+func pipelineMetadata(
+	target string,
+	results []pipelineResult,
+	previous map[string]string,
+	versions map[string]string,
+) ([]concourse.Metadata, error) {
+	metadata := []concourse.Metadata{}
+
+	for _, r := range results {
+		ref := r.Pipeline.Ref()
+		key := concourse.VersionKey(r.Pipeline.TeamName, ref.String())
+
+		m := concourse.PipelineMetadata{
+			Team:            r.Pipeline.TeamName,
+			Name:            r.Pipeline.Name,
+			InstanceVars:    r.Pipeline.InstanceVars,
+			Status:          r.Status,
+			Error:           reason(r),
+			PreviousVersion: previous[key],
+			Version:         versions[key],
+			URL:             concourse.PipelineURL(target, r.Pipeline.TeamName, ref),
+		}
+		m.Changed = m.Version != "" && m.Version != m.PreviousVersion
+
+		value, err := json.Marshal(m)
+		if err != nil {
+			// Untested as the metadata always marshals to JSON
+			return nil, err
+		}
+
+		metadata = append(metadata, concourse.Metadata{
+			Name:  key,
+			Value: string(value),
+		})
+	}
+
+	return metadata, nil
+}
+
+// reason returns why a pipeline failed or was skipped on a single line, as
+// errors from fly can span several.
+func reason(r pipelineResult) string {
+	if r.Err == nil {
+		return ""
+	}
+
+	return strings.Join(strings.Fields(r.Err.Error()), " ")
+}
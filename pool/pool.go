@@ -0,0 +1,57 @@
+package pool
+
+import "sync"
+
+// Run calls fn once for each index in [0, n), with at most concurrency calls
+// in flight at a time. Callers which need results should store them by index
+// so that their order does not depend on scheduling.
+//
+// Once any call returns an error no further calls are started, and the first
+// error is returned after the calls already in flight have finished. A
+// concurrency of less than one is treated as one.
+func Run(concurrency int, n int, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	failed := make(chan struct{})
+	slots := make(chan struct{}, concurrency)
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-failed:
+		case slots <- struct{}{}:
+		}
+
+		// Checked separately as select chooses randomly between ready cases.
+		select {
+		case <-failed:
+			wg.Wait()
+			return firstErr
+		default:
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := fn(i)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					close(failed)
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,85 @@
+package pool_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/concourse/concourse-pipeline-resource/pool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	It("calls the function once for each index", func() {
+		var mu sync.Mutex
+		called := make([]int, 10)
+
+		err := pool.Run(3, 10, func(i int) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			called[i]++
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(called).To(Equal([]int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
+	})
+
+	It("limits the number of calls in flight", func() {
+		var inFlight, maxInFlight int32
+
+		err := pool.Run(3, 20, func(i int) error {
+			current := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(maxInFlight).To(BeNumerically(">", 1))
+		Expect(maxInFlight).To(BeNumerically("<=", 3))
+	})
+
+	Context("when a call returns an error", func() {
+		It("stops starting calls and returns the error", func() {
+			var calls int32
+
+			err := pool.Run(1, 10, func(i int) error {
+				atomic.AddInt32(&calls, 1)
+
+				if i == 2 {
+					return fmt.Errorf("error at %d", i)
+				}
+				return nil
+			})
+			Expect(err).To(MatchError("error at 2"))
+
+			Expect(calls).To(Equal(int32(3)))
+		})
+	})
+
+	Context("when concurrency is less than one", func() {
+		It("runs the calls one at a time", func() {
+			var order []int
+
+			err := pool.Run(0, 5, func(i int) error {
+				order = append(order, i)
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(order).To(Equal([]int{0, 1, 2, 3, 4}))
+		})
+	})
+})
@@ -0,0 +1,92 @@
+package redact
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Secrets is a set of secret values, each with the text it is replaced by
+// when redacted. Secrets may be added after writers using the set have been
+// created, e.g. once vars files have been read.
+type Secrets struct {
+	mutex        sync.RWMutex
+	replacements map[string]string
+	ordered      []string
+}
+
+func NewSecrets() *Secrets {
+	return &Secrets{
+		replacements: make(map[string]string),
+	}
+}
+
+// Add registers secret to be replaced by replacement. Empty secrets are
+// ignored, and the first replacement registered for a secret is kept.
+func (s *Secrets) Add(secret string, replacement string) {
+	if secret == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, found := s.replacements[secret]; found {
+		return
+	}
+
+	s.replacements[secret] = replacement
+	s.ordered = append(s.ordered, secret)
+
+	// Longer secrets are replaced first so that a secret containing another
+	// is not left partially redacted.
+	sort.SliceStable(s.ordered, func(i, j int) bool {
+		return len(s.ordered[i]) > len(s.ordered[j])
+	})
+}
+
+// AddAll registers each secret in replacements, as returned by
+// concourse.SanitizedSource.
+func (s *Secrets) AddAll(replacements map[string]string) {
+	for secret, replacement := range replacements {
+		s.Add(secret, replacement)
+	}
+}
+
+// Redact returns str with each registered secret replaced.
+func (s *Secrets) Redact(str string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, secret := range s.ordered {
+		str = strings.Replace(str, secret, s.replacements[secret], -1)
+	}
+
+	return str
+}
+
+type writer struct {
+	secrets *Secrets
+	sink    io.Writer
+}
+
+// NewWriter returns a writer which redacts secrets from everything written
+// to it before writing to sink.
+func NewWriter(secrets *Secrets, sink io.Writer) io.Writer {
+	return &writer{
+		secrets: secrets,
+		sink:    sink,
+	}
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	_, err := w.sink.Write([]byte(w.secrets.Redact(string(p))))
+	if err != nil {
+		return 0, err
+	}
+
+	// Report the length of the original input so callers do not treat the
+	// redacted write as a short write.
+	return len(p), nil
+}
@@ -0,0 +1,70 @@
+package redact_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/concourse/concourse-pipeline-resource/redact"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redact", func() {
+	var (
+		secrets *redact.Secrets
+	)
+
+	BeforeEach(func() {
+		secrets = redact.NewSecrets()
+		secrets.Add("some-password", "***REDACTED-PASSWORD***")
+	})
+
+	Describe("Redact", func() {
+		It("replaces each secret", func() {
+			redacted := secrets.Redact("login -p some-password; again: some-password")
+			Expect(redacted).To(Equal("login -p ***REDACTED-PASSWORD***; again: ***REDACTED-PASSWORD***"))
+		})
+
+		It("ignores empty secrets", func() {
+			secrets.Add("", "***EMPTY***")
+			Expect(secrets.Redact("nothing secret")).To(Equal("nothing secret"))
+		})
+
+		It("keeps the first replacement for a secret", func() {
+			secrets.Add("some-password", "***OTHER***")
+			Expect(secrets.Redact("some-password")).To(Equal("***REDACTED-PASSWORD***"))
+		})
+
+		Context("when one secret contains another", func() {
+			BeforeEach(func() {
+				secrets.Add("pass", "***REDACTED-SHORT***")
+			})
+
+			It("replaces the longer secret first", func() {
+				Expect(secrets.Redact("some-password pass")).To(Equal("***REDACTED-PASSWORD*** ***REDACTED-SHORT***"))
+			})
+		})
+	})
+
+	Describe("NewWriter", func() {
+		var (
+			sink *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			sink = &bytes.Buffer{}
+		})
+
+		It("redacts secrets added after the writer is created", func() {
+			w := redact.NewWriter(secrets, sink)
+
+			secrets.AddAll(map[string]string{"some-token": "***REDACTED-TOKEN***"})
+
+			n, err := fmt.Fprintf(w, "-y token=some-token -p some-password\n")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(len("-y token=some-token -p some-password\n")))
+
+			Expect(sink.String()).To(Equal("-y token=***REDACTED-TOKEN*** -p ***REDACTED-PASSWORD***\n"))
+		})
+	})
+})
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"gopkg.in/yaml.v2"
+)
+
+// Engine renders the config at configFilepath with the templating engine
+// configured by options, returning the rendered config. Inputs are relative
+// to sourcesDir.
+func Engine(configFilepath string, options concourse.Render, sourcesDir string) ([]byte, error) {
+	var inputs []string
+	for _, i := range options.Inputs {
+		inputs = append(inputs, filepath.Join(sourcesDir, i))
+	}
+
+	switch options.Engine {
+	case concourse.RenderEngineTemplate:
+		return renderTemplate(configFilepath, inputs, options.Data)
+	case concourse.RenderEngineYtt:
+		args := []string{"-f", configFilepath}
+		for _, i := range inputs {
+			args = append(args, "-f", i)
+		}
+
+		for _, k := range concourse.SortedKeys(options.Data) {
+			value, err := yaml.Marshal(options.Data[k])
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--data-value-yaml", fmt.Sprintf("%s=%s", k, strings.TrimSpace(string(value))))
+		}
+
+		return runBinary(binary(options, sourcesDir), args)
+	case concourse.RenderEngineJsonnet:
+		var args []string
+		for _, i := range inputs {
+			args = append(args, "-J", i)
+		}
+
+		for _, k := range concourse.SortedKeys(options.Data) {
+			value, err := json.Marshal(concourse.JSONCompatible(options.Data[k]))
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "--ext-code", fmt.Sprintf("%s=%s", k, value))
+		}
+
+		return runBinary(binary(options, sourcesDir), append(args, configFilepath))
+	default:
+		return nil, fmt.Errorf("unknown render engine: %s", options.Engine)
+	}
+}
+
+// renderTemplate executes the config as a Go text/template with data. Each
+// input is parsed as a named template which the config can include, e.g.
+// `{{template "jobs.yml" .}}`.
+func renderTemplate(configFilepath string, inputs []string, data map[string]interface{}) ([]byte, error) {
+	t, err := template.New(filepath.Base(configFilepath)).
+		Option("missingkey=error").
+		ParseFiles(append([]string{configFilepath}, inputs...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	err = t.ExecuteTemplate(&b, filepath.Base(configFilepath), data)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// binary returns the path to the engine's binary. A relative path, such as
+// one in the output of a get step, is relative to sourcesDir; a bare name is
+// looked up on the PATH.
+func binary(options concourse.Render, sourcesDir string) string {
+	if options.Binary == "" {
+		return options.Engine
+	}
+
+	if !filepath.IsAbs(options.Binary) && strings.Contains(options.Binary, "/") {
+		return filepath.Join(sourcesDir, options.Binary)
+	}
+
+	return options.Binary
+}
+
+func runBinary(path string, args []string) ([]byte, error) {
+	cmd := exec.Command(path, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %v - stderr: %s", filepath.Base(path), err, stderr.String())
+	}
+
+	return out, nil
+}
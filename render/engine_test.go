@@ -0,0 +1,133 @@
+package render_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/render"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Engine", func() {
+	var (
+		tempDir        string
+		configFilepath string
+		options        concourse.Render
+	)
+
+	writeFile := func(name string, contents string) string {
+		p := filepath.Join(tempDir, name)
+		err := ioutil.WriteFile(p, []byte(contents), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+		return p
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		configFilepath = writeFile("pipeline.yml", "config")
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("template", func() {
+		BeforeEach(func() {
+			configFilepath = writeFile("pipeline.yml", `jobs:
+{{- range .envs}}
+- name: deploy-{{.}}
+{{- end}}
+{{template "resources.yml" .}}`)
+
+			writeFile("resources.yml", `resources:
+- name: {{.repo}}`)
+
+			options = concourse.Render{
+				Engine: concourse.RenderEngineTemplate,
+				Inputs: []string{"resources.yml"},
+				Data: map[string]interface{}{
+					"envs": []interface{}{"staging", "production"},
+					"repo": "some-repo",
+				},
+			}
+		})
+
+		It("executes the config with the data and inputs", func() {
+			rendered, err := render.Engine(configFilepath, options, tempDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(rendered)).To(Equal(`jobs:
+- name: deploy-staging
+- name: deploy-production
+resources:
+- name: some-repo`))
+		})
+
+		Context("when the data is missing a key", func() {
+			BeforeEach(func() {
+				delete(options.Data, "repo")
+			})
+
+			It("returns an error", func() {
+				_, err := render.Engine(configFilepath, options, tempDir)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("external engines", func() {
+		BeforeEach(func() {
+			fakeBinary := writeFile("fake-engine", "#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done\n")
+
+			options = concourse.Render{
+				Inputs: []string{"lib"},
+				Data: map[string]interface{}{
+					"env":  "staging",
+					"tags": map[interface{}]interface{}{"team": "main"},
+				},
+				Binary: fakeBinary,
+			}
+		})
+
+		It("invokes ytt with files and data values", func() {
+			options.Engine = concourse.RenderEngineYtt
+
+			rendered, err := render.Engine(configFilepath, options, tempDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(rendered)).To(Equal("-f\n" + configFilepath + "\n-f\n" + filepath.Join(tempDir, "lib") + "\n" +
+				"--data-value-yaml\nenv=staging\n--data-value-yaml\ntags=team: main\n"))
+		})
+
+		It("invokes jsonnet with library paths and external code", func() {
+			options.Engine = concourse.RenderEngineJsonnet
+
+			rendered, err := render.Engine(configFilepath, options, tempDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(rendered)).To(Equal("-J\n" + filepath.Join(tempDir, "lib") + "\n" +
+				"--ext-code\nenv=\"staging\"\n--ext-code\ntags={\"team\":\"main\"}\n" + configFilepath + "\n"))
+		})
+
+		Context("when the engine fails", func() {
+			BeforeEach(func() {
+				options.Binary = writeFile("failing-engine", "#!/bin/sh\necho some failure >&2\nexit 1\n")
+				options.Engine = concourse.RenderEngineYtt
+			})
+
+			It("returns an error including stderr", func() {
+				_, err := render.Engine(configFilepath, options, tempDir)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(ContainSubstring("some failure"))
+			})
+		})
+	})
+})
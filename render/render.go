@@ -0,0 +1,23 @@
+package render
+
+import "io/ioutil"
+
+// Config reads the pipeline config at configFilepath and interpolates the
+// vars from varsFilepaths and vars into it, as `fly set-pipeline` would.
+func Config(
+	configFilepath string,
+	varsFilepaths []string,
+	vars map[string]interface{},
+) ([]byte, error) {
+	config, err := ioutil.ReadFile(configFilepath)
+	if err != nil {
+		return nil, err
+	}
+
+	allVars, err := LoadVars(varsFilepaths, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return Interpolate(config, allVars)
+}
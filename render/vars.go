@@ -0,0 +1,177 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var varRegex = regexp.MustCompile(`\(\(\s*([-/\.\w\pL:]+)\s*\)\)`)
+
+// LoadVars reads each vars file in order and then overlays vars, mirroring
+// the precedence of `-l` and `-y` flags to `fly set-pipeline`.
+func LoadVars(varsFilepaths []string, vars map[string]interface{}) (map[string]interface{}, error) {
+	loaded := make(map[string]interface{})
+
+	for _, vf := range varsFilepaths {
+		b, err := ioutil.ReadFile(vf)
+		if err != nil {
+			return nil, err
+		}
+
+		var fileVars map[string]interface{}
+		err = yaml.Unmarshal(b, &fileVars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vars file %s: %v", vf, err)
+		}
+
+		for k, v := range fileVars {
+			loaded[k] = v
+		}
+	}
+
+	for k, v := range vars {
+		loaded[k] = v
+	}
+
+	return loaded, nil
+}
+
+// Interpolate replaces each `((var))` in the given YAML config with its
+// value. A string consisting solely of a var is replaced by the value
+// itself, preserving its type; vars embedded in a longer string are
+// replaced by their string form. Vars which are not provided are left in
+// place so they can be resolved at runtime by a credential manager.
+func Interpolate(config []byte, vars map[string]interface{}) ([]byte, error) {
+	var tree interface{}
+	err := yaml.Unmarshal(config, &tree)
+	if err != nil {
+		return nil, err
+	}
+
+	interpolated, err := interpolate(tree, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(interpolated)
+}
+
+func interpolate(node interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		interpolatedMap := make(map[interface{}]interface{}, len(n))
+		for k, v := range n {
+			interpolatedKey, err := interpolate(k, vars)
+			if err != nil {
+				return nil, err
+			}
+
+			interpolatedValue, err := interpolate(v, vars)
+			if err != nil {
+				return nil, err
+			}
+
+			interpolatedMap[interpolatedKey] = interpolatedValue
+		}
+		return interpolatedMap, nil
+	case []interface{}:
+		for i, v := range n {
+			interpolated, err := interpolate(v, vars)
+			if err != nil {
+				return nil, err
+			}
+			n[i] = interpolated
+		}
+		return n, nil
+	case string:
+		return interpolateString(n, vars)
+	default:
+		return node, nil
+	}
+}
+
+func interpolateString(s string, vars map[string]interface{}) (interface{}, error) {
+	if match := varRegex.FindStringSubmatch(s); match != nil && match[0] == s {
+		if value, found := lookup(match[1], vars); found {
+			return value, nil
+		}
+		return s, nil
+	}
+
+	var err error
+	interpolated := varRegex.ReplaceAllStringFunc(s, func(v string) string {
+		name := varRegex.FindStringSubmatch(v)[1]
+
+		value, found := lookup(name, vars)
+		if !found {
+			return v
+		}
+
+		switch value.(type) {
+		case string, bool, int, int64, float64:
+			return fmt.Sprintf("%v", value)
+		default:
+			err = fmt.Errorf("var (%s) of type %T cannot be interpolated into a string", name, value)
+			return v
+		}
+	})
+
+	return interpolated, err
+}
+
+// lookup resolves a var name, following dotted paths into nested maps.
+func lookup(name string, vars map[string]interface{}) (interface{}, bool) {
+	if strings.Contains(name, ":") {
+		// Vars from a named var source are resolved by Concourse.
+		return nil, false
+	}
+
+	segments := strings.Split(name, ".")
+
+	var current interface{} = vars
+	for _, segment := range segments {
+		switch m := current.(type) {
+		case map[string]interface{}:
+			v, found := m[segment]
+			if !found {
+				return nil, false
+			}
+			current = v
+		case map[interface{}]interface{}:
+			v, found := m[segment]
+			if !found {
+				return nil, false
+			}
+			current = v
+		default:
+			return nil, false
+		}
+	}
+
+	return normalize(current), true
+}
+
+// normalize converts values decoded from JSON into types which marshal to
+// YAML the same way as values decoded from YAML.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,137 @@
+package render_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/render"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("Vars", func() {
+	var (
+		tempDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("LoadVars", func() {
+		var (
+			varsFilepaths []string
+		)
+
+		BeforeEach(func() {
+			varsFilepaths = []string{
+				filepath.Join(tempDir, "vars-1.yml"),
+				filepath.Join(tempDir, "vars-2.yml"),
+			}
+
+			err := ioutil.WriteFile(varsFilepaths[0], []byte("a: 1\nb: 1\n"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(varsFilepaths[1], []byte("b: 2\nc: 2\n"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("overlays later vars files and then vars", func() {
+			vars, err := render.LoadVars(varsFilepaths, map[string]interface{}{"c": 3})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(vars).To(Equal(map[string]interface{}{
+				"a": 1,
+				"b": 2,
+				"c": 3,
+			}))
+		})
+
+		Context("when a vars file does not exist", func() {
+			It("returns an error", func() {
+				_, err := render.LoadVars([]string{filepath.Join(tempDir, "missing.yml")}, nil)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Interpolate", func() {
+		var (
+			config []byte
+			vars   map[string]interface{}
+		)
+
+		BeforeEach(func() {
+			config = []byte(`---
+resources:
+- name: repo
+  type: git
+  source:
+    uri: ((uri))
+    branch: release-((version))
+    private_key: ((vault:private_key))
+    credentials: ((credentials))
+    username: ((credentials.username))
+    password: ((unknown))
+`)
+
+			vars = map[string]interface{}{
+				"uri":     "https://example.com/repo.git",
+				"version": 2,
+				"credentials": map[interface{}]interface{}{
+					"username": "admin",
+				},
+			}
+		})
+
+		It("replaces provided vars and leaves others in place", func() {
+			output, err := render.Interpolate(config, vars)
+			Expect(err).NotTo(HaveOccurred())
+
+			var rendered map[string][]map[string]interface{}
+			err = yaml.Unmarshal(output, &rendered)
+			Expect(err).NotTo(HaveOccurred())
+
+			source := rendered["resources"][0]["source"]
+			Expect(source).To(Equal(map[interface{}]interface{}{
+				"uri":         "https://example.com/repo.git",
+				"branch":      "release-2",
+				"private_key": "((vault:private_key))",
+				"credentials": map[interface{}]interface{}{"username": "admin"},
+				"username":    "admin",
+				"password":    "((unknown))",
+			}))
+		})
+
+		Context("when a non-scalar var is embedded in a string", func() {
+			BeforeEach(func() {
+				config = []byte("key: prefix-((credentials))\n")
+			})
+
+			It("returns an error", func() {
+				_, err := render.Interpolate(config, vars)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the config is not valid YAML", func() {
+			BeforeEach(func() {
+				config = []byte("key: [")
+			})
+
+			It("returns an error", func() {
+				_, err := render.Interpolate(config, vars)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
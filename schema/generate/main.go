@@ -0,0 +1,32 @@
+// generate writes each schema published by the schema package to the
+// given directory.
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/schema"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("not enough args - usage: %s <output directory>", os.Args[0])
+	}
+
+	outputDir := os.Args[1]
+
+	for name, s := range schema.Documents() {
+		b, err := schema.Marshal(s)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		err = ioutil.WriteFile(filepath.Join(outputDir, name), b, 0644)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
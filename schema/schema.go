@@ -0,0 +1,162 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+//go:generate go run ./generate .
+
+const draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is the subset of JSON Schema needed to describe the resource's
+// configuration.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type    string   `json:"type,omitempty"`
+	Enum    []string `json:"enum,omitempty"`
+	Minimum *int     `json:"minimum,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+
+	// AdditionalProperties is either false, for structs, or the schema of
+	// each value, for maps.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+
+	Items *Schema `json:"items,omitempty"`
+}
+
+// PipelinesFile is the contents of the file referred to by pipelines_file.
+type PipelinesFile struct {
+	Pipelines []concourse.Pipeline `json:"pipelines" yaml:"pipelines" required:"true" description:"Pipelines to set."`
+}
+
+// Source returns the schema of the resource's source.
+func Source() *Schema {
+	return document("source", "Source configuration of the concourse-pipeline-resource.", concourse.Source{})
+}
+
+// OutParams returns the schema of the params of the resource's put step.
+func OutParams() *Schema {
+	return document("params", "Params of a put to the concourse-pipeline-resource.", concourse.OutParams{})
+}
+
+// PipelinesFileSchema returns the schema of the file referred to by
+// pipelines_file.
+func PipelinesFileSchema() *Schema {
+	return document("pipelines_file", "Pipelines to set with the concourse-pipeline-resource.", PipelinesFile{})
+}
+
+// Documents returns each published schema by its file name.
+func Documents() map[string]*Schema {
+	return map[string]*Schema{
+		"source.schema.json":         Source(),
+		"out_params.schema.json":     OutParams(),
+		"pipelines_file.schema.json": PipelinesFileSchema(),
+	}
+}
+
+func document(title string, description string, v interface{}) *Schema {
+	s := For(reflect.TypeOf(v))
+	s.Schema = draft
+	s.Title = title
+	s.Description = description
+	return s
+}
+
+// Of returns the schema of values of the same type as v, e.g. a request.
+func Of(v interface{}) *Schema {
+	return For(reflect.TypeOf(v))
+}
+
+// For returns the schema of values of type t. Struct fields are described
+// by their json, description, enum, minimum and required tags.
+func For(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{
+			Type:                 "object",
+			Properties:           make(map[string]*Schema),
+			AdditionalProperties: false,
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				// unexported
+				continue
+			}
+
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			property := For(field.Type)
+			property.Description = field.Tag.Get("description")
+
+			if enum := field.Tag.Get("enum"); enum != "" {
+				property.Enum = strings.Split(enum, ",")
+			}
+
+			if minimum, err := strconv.Atoi(field.Tag.Get("minimum")); err == nil {
+				property.Minimum = &minimum
+			}
+
+			if field.Tag.Get("required") == "true" {
+				s.Required = append(s.Required, name)
+			}
+
+			s.Properties[name] = property
+		}
+
+		return s
+	case reflect.Slice, reflect.Array:
+		return &Schema{
+			Type:  "array",
+			Items: For(t.Elem()),
+		}
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: For(t.Elem()),
+		}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		// interface{} accepts any value.
+		return &Schema{}
+	}
+}
+
+// Marshal returns the indented JSON of s, as published.
+func Marshal(s *Schema) ([]byte, error) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,75 @@
+package schema_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/concourse/concourse-pipeline-resource/schema"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schema", func() {
+	Describe("Source", func() {
+		var (
+			s *schema.Schema
+		)
+
+		BeforeEach(func() {
+			s = schema.Source()
+		})
+
+		It("describes an object which rejects unknown fields", func() {
+			Expect(s.Schema).To(Equal("http://json-schema.org/draft-07/schema#"))
+			Expect(s.Type).To(Equal("object"))
+			Expect(s.AdditionalProperties).To(Equal(false))
+			Expect(s.Required).To(Equal([]string{"teams"}))
+		})
+
+		It("derives properties from the json, description, enum and minimum tags", func() {
+			client := s.Properties["client"]
+			Expect(client.Type).To(Equal("string"))
+			Expect(client.Enum).To(Equal([]string{"fly", "api"}))
+			Expect(client.Description).NotTo(BeEmpty())
+
+			maxConcurrency := s.Properties["max_concurrency"]
+			Expect(maxConcurrency.Type).To(Equal("integer"))
+			Expect(*maxConcurrency.Minimum).To(Equal(0))
+		})
+
+		It("describes nested types", func() {
+			teams := s.Properties["teams"]
+			Expect(teams.Type).To(Equal("array"))
+			Expect(teams.Items.Type).To(Equal("object"))
+			Expect(teams.Items.Required).To(Equal([]string{"name"}))
+			Expect(teams.Items.Properties["pipelines"].Properties["include"].Items.Type).To(Equal("string"))
+		})
+	})
+
+	Describe("PipelinesFileSchema", func() {
+		It("allows vars of any type", func() {
+			pipeline := schema.PipelinesFileSchema().Properties["pipelines"].Items
+
+			Expect(pipeline.Required).To(Equal([]string{"team"}))
+			Expect(pipeline.Properties["vars"].Type).To(Equal("object"))
+			Expect(pipeline.Properties["vars"].AdditionalProperties).To(Equal(&schema.Schema{}))
+		})
+	})
+
+	Describe("published documents", func() {
+		It("are up to date with the Go types", func() {
+			for name, s := range schema.Documents() {
+				published, err := ioutil.ReadFile(name)
+				if os.IsNotExist(err) {
+					Skip("published schemas are only available in the source tree")
+				}
+				Expect(err).NotTo(HaveOccurred())
+
+				generated, err := schema.Marshal(s)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(string(published)).To(Equal(string(generated)), "%s is out of date; run `go generate ./schema`", name)
+			}
+		})
+	})
+})
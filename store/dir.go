@@ -0,0 +1,69 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// dirStore is a store in a local directory.
+type dirStore struct {
+	dir string
+}
+
+func (s *dirStore) Get(teamName string, pipelineName string, version string) ([]byte, error) {
+	config, err := ioutil.ReadFile(s.path(teamName, pipelineName, version))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = verify(config, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (s *dirStore) Put(teamName string, pipelineName string, config []byte) error {
+	entry := s.path(teamName, pipelineName, Version(config))
+
+	// Entries are named by their contents, so one which exists is never
+	// rewritten.
+	if _, err := os.Stat(entry); err == nil {
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(entry), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	// The entry is written to a temporary file and renamed into place, so
+	// that a partially written entry is never read.
+	tmp, err := ioutil.TempFile(filepath.Dir(entry), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(config)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), entry)
+}
+
+func (s *dirStore) path(teamName string, pipelineName string, version string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(entryPath(teamName, pipelineName, version)))
+}
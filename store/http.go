@@ -0,0 +1,91 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// httpStore is a store on an HTTP server, such as a bucket or an artifact
+// repository, which serves entries with GET and stores them with PUT.
+type httpStore struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newHTTPStore(url string, token string) *httpStore {
+	return &httpStore{
+		url:    strings.TrimSuffix(url, "/"),
+		token:  token,
+		client: http.DefaultClient,
+	}
+}
+
+func (s *httpStore) Get(teamName string, pipelineName string, version string) ([]byte, error) {
+	resp, err := s.do("GET", entryPath(teamName, pipelineName, version), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	config, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("archive responded to GET with %d: %s", resp.StatusCode, string(config))
+	}
+
+	err = verify(config, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (s *httpStore) Put(teamName string, pipelineName string, config []byte) error {
+	resp, err := s.do("PUT", entryPath(teamName, pipelineName, Version(config)), config)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("archive responded to PUT with %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func (s *httpStore) do(method string, entry string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, s.url+"/"+entry, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-yaml")
+	}
+
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	return s.client.Do(req)
+}
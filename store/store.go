@@ -0,0 +1,71 @@
+package store
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+)
+
+//go:generate counterfeiter . Store
+
+// Store keeps the config of every version of a pipeline, so that a version
+// can be fetched once the pipeline has moved on from it. Versions are the
+// MD5 checksums of the configs, as in the versions of the resource. A store
+// may be used concurrently.
+type Store interface {
+	// Get returns the config of the version of a pipeline, or ErrNotFound.
+	Get(teamName string, pipelineName string, version string) ([]byte, error)
+	// Put stores the config of a pipeline under its version.
+	Put(teamName string, pipelineName string, config []byte) error
+}
+
+// ErrNotFound is returned by Get if a version is not in the store.
+var ErrNotFound = errors.New("version not found in archive")
+
+// New returns the store described by archive: a directory for a file URL,
+// or an HTTP server which configs are fetched from with GET and stored with
+// PUT. It returns nil if archive is nil.
+func New(archive *concourse.Archive) (Store, error) {
+	if archive == nil {
+		return nil, nil
+	}
+
+	u, err := url.Parse(archive.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		return &dirStore{dir: u.Path}, nil
+	case "http", "https":
+		return newHTTPStore(archive.URL, archive.Token), nil
+	default:
+		return nil, fmt.Errorf("unsupported archive URL scheme '%s'", u.Scheme)
+	}
+}
+
+// Version returns the version of a config.
+func Version(config []byte) string {
+	return fmt.Sprintf("%x", md5.Sum(config))
+}
+
+// entryPath returns the slash-separated path of the config of a version,
+// `<team>/<pipeline>/<version>.yml`, relative to the root of the store.
+func entryPath(teamName string, pipelineName string, version string) string {
+	return path.Join(url.PathEscape(teamName), url.PathEscape(pipelineName), version+".yml")
+}
+
+// verify returns an error unless config is of the version, so that a
+// corrupt entry is never returned as the version.
+func verify(config []byte, version string) error {
+	if actual := Version(config); actual != version {
+		return fmt.Errorf("archived config of version %s has version %s", version, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package store_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/internal/testserver"
+	"github.com/concourse/concourse-pipeline-resource/store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		config  []byte
+		version string
+	)
+
+	BeforeEach(func() {
+		config = []byte("jobs: []\n")
+		version = store.Version(config)
+	})
+
+	Describe("New", func() {
+		It("returns nil if no archive is configured", func() {
+			s, err := store.New(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s).To(BeNil())
+		})
+
+		It("returns an error for an unsupported URL", func() {
+			_, err := store.New(&concourse.Archive{URL: "s3://bucket"})
+			Expect(err).To(MatchError("unsupported archive URL scheme 's3'"))
+		})
+	})
+
+	Describe("a directory", func() {
+		var (
+			dir string
+			s   store.Store
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			s, err = store.New(&concourse.Archive{URL: "file://" + dir})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			err := os.RemoveAll(dir)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the config which was put under its version", func() {
+			err := s.Put("main", "some-pipeline/branch:main", config)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(dir, "main", "some-pipeline%2Fbranch:main", version+".yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(config))
+
+			stored, err := s.Get("main", "some-pipeline/branch:main", version)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stored).To(Equal(config))
+		})
+
+		It("returns ErrNotFound for a version which was not put", func() {
+			_, err := s.Get("main", "some-pipeline", version)
+			Expect(err).To(Equal(store.ErrNotFound))
+		})
+
+		Context("when an entry is corrupt", func() {
+			BeforeEach(func() {
+				err := os.MkdirAll(filepath.Join(dir, "main", "some-pipeline"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = ioutil.WriteFile(filepath.Join(dir, "main", "some-pipeline", version+".yml"), []byte("jobs: [corrupt]\n"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns an error", func() {
+				_, err := s.Get("main", "some-pipeline", version)
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("archived config of version %s has version", version))))
+			})
+		})
+	})
+
+	Describe("an HTTP server", func() {
+		var (
+			server *testserver.Server
+			s      store.Store
+		)
+
+		BeforeEach(func() {
+			server = testserver.NewServer()
+
+			var err error
+			s, err = store.New(&concourse.Archive{URL: server.URL() + "/pipelines/", Token: "some-token"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Describe("Put", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("PUT", "/pipelines/main/some-pipeline/"+version+".yml"),
+						testserver.VerifyHeaderKV("Authorization", "Bearer some-token"),
+						testserver.VerifyBody(config),
+						testserver.RespondWith(http.StatusCreated, ""),
+					),
+				)
+			})
+
+			It("puts the config under its version", func() {
+				err := s.Put("main", "some-pipeline", config)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			Context("when the server responds with an error", func() {
+				BeforeEach(func() {
+					server.SetHandler(0, testserver.RespondWith(http.StatusForbidden, "denied"))
+				})
+
+				It("returns an error", func() {
+					err := s.Put("main", "some-pipeline", config)
+					Expect(err).To(MatchError("archive responded to PUT with 403: denied"))
+				})
+			})
+		})
+
+		Describe("Get", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					testserver.CombineHandlers(
+						testserver.VerifyRequest("GET", "/pipelines/main/some-pipeline/"+version+".yml"),
+						testserver.VerifyHeaderKV("Authorization", "Bearer some-token"),
+						testserver.RespondWith(http.StatusOK, config),
+					),
+				)
+			})
+
+			It("returns the config of the version", func() {
+				stored, err := s.Get("main", "some-pipeline", version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stored).To(Equal(config))
+			})
+
+			Context("when the version is not found", func() {
+				BeforeEach(func() {
+					server.SetHandler(0, testserver.RespondWith(http.StatusNotFound, ""))
+				})
+
+				It("returns ErrNotFound", func() {
+					_, err := s.Get("main", "some-pipeline", version)
+					Expect(err).To(Equal(store.ErrNotFound))
+				})
+			})
+
+			Context("when the config is not of the version", func() {
+				BeforeEach(func() {
+					server.SetHandler(0, testserver.RespondWith(http.StatusOK, "jobs: [other]\n"))
+				})
+
+				It("returns an error", func() {
+					_, err := s.Get("main", "some-pipeline", version)
+					Expect(err).To(MatchError(ContainSubstring("archived config of version")))
+				})
+			})
+		})
+	})
+})
@@ -1,15 +1,15 @@
 package validator
 
 import (
-	"fmt"
-
 	"github.com/concourse/concourse-pipeline-resource/concourse"
 )
 
 func ValidateCheck(input concourse.CheckRequest) error {
-	if input.Source.Target == "" {
-		return fmt.Errorf("%s must be provided in source", "target")
-	}
+	c := &collector{}
+
+	validateTarget(c, input.Source)
+	validateSource(c, input.Source)
+	validateTeams(c, input.Source.Teams)
 
-	return ValidateTeams(input.Source.Teams)
+	return c.err()
 }
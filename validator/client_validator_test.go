@@ -0,0 +1,30 @@
+package validator_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/validator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateClient", func() {
+	It("accepts no client", func() {
+		Expect(validator.ValidateClient("")).To(Succeed())
+	})
+
+	It("accepts the fly client", func() {
+		Expect(validator.ValidateClient("fly")).To(Succeed())
+	})
+
+	It("accepts the api client", func() {
+		Expect(validator.ValidateClient("api")).To(Succeed())
+	})
+
+	Context("when the client is unknown", func() {
+		It("returns an error", func() {
+			err := validator.ValidateClient("some-client")
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*client.*one of"))
+		})
+	})
+})
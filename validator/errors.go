@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with the field at Path, e.g.
+// `params.pipelines[3].vars_files[0]`.
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Errors is every problem found while validating a request, in the order
+// the fields appear in the request.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	lines := []string{fmt.Sprintf("%d validation errors:", len(e))}
+	for _, fe := range e {
+		lines = append(lines, "  - "+fe.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+type collector struct {
+	errs Errors
+}
+
+func (c *collector) add(path string, format string, a ...interface{}) {
+	c.errs = append(c.errs, FieldError{
+		Path:    path,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// err returns the problems found, or nil if there were none, so that callers
+// never receive a non-nil error holding an empty Errors.
+func (c *collector) err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+
+	return c.errs
+}
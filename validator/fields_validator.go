@@ -0,0 +1,218 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidateJSONFields returns an error for every key in the JSON document b
+// which does not correspond to a field of v, which would otherwise be
+// silently ignored when b is decoded into v.
+func ValidateJSONFields(b []byte, v interface{}) error {
+	var data interface{}
+	err := json.Unmarshal(b, &data)
+	if err != nil {
+		return err
+	}
+
+	c := &collector{}
+	validateFields(c, "", data, reflect.TypeOf(v), jsonFieldNames)
+	return c.err()
+}
+
+// ValidateYAMLFields is ValidateJSONFields for YAML documents. Paths in the
+// returned errors are prefixed by root, e.g. the name of the file.
+func ValidateYAMLFields(b []byte, root string, v interface{}) error {
+	var data interface{}
+	err := yaml.Unmarshal(b, &data)
+	if err != nil {
+		return err
+	}
+
+	c := &collector{}
+	validateFields(c, root, data, reflect.TypeOf(v), yamlFieldNames)
+	return c.err()
+}
+
+// fieldNamer returns the key which decodes into field, and whether keys
+// are matched to it case-insensitively.
+type fieldNamer func(field reflect.StructField) (name string, caseInsensitive bool)
+
+func jsonFieldNames(field reflect.StructField) (string, bool) {
+	return tagName(field, "json", field.Name), true
+}
+
+func yamlFieldNames(field reflect.StructField) (string, bool) {
+	return tagName(field, "yaml", strings.ToLower(field.Name)), false
+}
+
+func tagName(field reflect.StructField, tag string, defaultName string) string {
+	name := strings.Split(field.Tag.Get(tag), ",")[0]
+	if name == "" {
+		return defaultName
+	}
+	return name
+}
+
+func validateFields(c *collector, path string, data interface{}, t reflect.Type, namer fieldNamer) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		entries := mapEntries(data)
+		for _, key := range concourse.SortedKeys(entries) {
+			value := entries[key]
+
+			field, found := lookupField(t, key, namer)
+			if !found {
+				c.add(joinPath(path, key), "%s", unknownFieldMessage(key, t, namer))
+				continue
+			}
+
+			validateFields(c, joinPath(path, key), value, field.Type, namer)
+		}
+	case reflect.Slice, reflect.Array:
+		if items, ok := data.([]interface{}); ok {
+			for i, item := range items {
+				validateFields(c, fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), namer)
+			}
+		}
+	case reflect.Map:
+		entries := mapEntries(data)
+		for _, key := range concourse.SortedKeys(entries) {
+			validateFields(c, joinPath(path, key), entries[key], t.Elem(), namer)
+		}
+	}
+}
+
+// mapEntries returns the entries of a map decoded from JSON or YAML, or nil
+// if data is not a map. Type mismatches are reported when decoding.
+func mapEntries(data interface{}) map[string]interface{} {
+	switch m := data.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		entries := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			entries[fmt.Sprintf("%v", k)] = v
+		}
+		return entries
+	default:
+		return nil
+	}
+}
+
+func lookupField(t reflect.Type, key string, namer fieldNamer) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		name, caseInsensitive := namer(field)
+		if name == "-" {
+			continue
+		}
+
+		if name == key || (caseInsensitive && strings.EqualFold(name, key)) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func unknownFieldMessage(key string, t reflect.Type, namer fieldNamer) string {
+	message := fmt.Sprintf("unknown field %s", key)
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			continue
+		}
+
+		name, _ := namer(t.Field(i))
+		if name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	if suggestion, found := closest(key, names); found {
+		message += fmt.Sprintf(", did you mean %s?", suggestion)
+	}
+
+	return message
+}
+
+// closest returns the name with the smallest edit distance from key, if it
+// is close enough to be a likely typo.
+func closest(key string, names []string) (string, bool) {
+	sort.Strings(names)
+
+	best := ""
+	bestDistance := -1
+	for _, name := range names {
+		d := levenshtein(strings.ToLower(key), strings.ToLower(name))
+		if bestDistance == -1 || d < bestDistance {
+			best = name
+			bestDistance = d
+		}
+	}
+
+	maxDistance := len(key) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	return best, bestDistance != -1 && bestDistance <= maxDistance
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minimum(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func minimum(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,63 @@
+package validator_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/validator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateJSONFields", func() {
+	It("accepts known fields, in any case", func() {
+		request := []byte(`{
+			"source": {"Target": "some target", "teams": [{"name": "main", "pipelines": {"include": ["*"]}}]},
+			"params": {"pipelines": [{"name": "p1", "vars": {"anything": {"goes": true}}}]}
+		}`)
+
+		err := validator.ValidateJSONFields(request, concourse.OutRequest{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns every unknown field with a suggestion", func() {
+		request := []byte(`{
+			"source": {"target": "some target", "team": []},
+			"params": {"pipelines": [{"name": "p1"}, {"name": "p2", "unpause": true, "var_files": ["vars.yml"], "colour": "blue"}]}
+		}`)
+
+		err := validator.ValidateJSONFields(request, concourse.OutRequest{})
+		Expect(err).To(HaveOccurred())
+
+		errs, ok := err.(validator.Errors)
+		Expect(ok).To(BeTrue())
+		Expect(errs).To(Equal(validator.Errors{
+			{Path: "params.pipelines[1].colour", Message: "unknown field colour"},
+			{Path: "params.pipelines[1].unpause", Message: "unknown field unpause, did you mean unpaused?"},
+			{Path: "params.pipelines[1].var_files", Message: "unknown field var_files, did you mean vars_files?"},
+			{Path: "source.team", Message: "unknown field team, did you mean teams?"},
+		}))
+	})
+
+	Context("when the document is not valid JSON", func() {
+		It("returns an error", func() {
+			err := validator.ValidateJSONFields([]byte(`{`), concourse.CheckRequest{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("ValidateYAMLFields", func() {
+	It("prefixes paths with the root", func() {
+		pipelinesFile := []byte(`
+pipelines:
+- name: p1
+  team: main
+  config_file: pipeline.yml
+  unpause: true
+`)
+
+		err := validator.ValidateYAMLFields(pipelinesFile, "pipelines.yml", concourse.OutParams{})
+		Expect(err).To(HaveOccurred())
+
+		Expect(err.Error()).To(Equal("pipelines.yml.pipelines[0].unpause: unknown field unpause, did you mean unpaused?"))
+	})
+})
@@ -2,14 +2,80 @@ package validator
 
 import (
 	"fmt"
+	"text/template"
 
 	"github.com/concourse/concourse-pipeline-resource/concourse"
 )
 
 func ValidateIn(input concourse.InRequest) error {
-	if input.Source.Target == "" {
-		return fmt.Errorf("%s must be provided in source", "target")
+	c := &collector{}
+
+	validateTarget(c, input.Source)
+	validateSource(c, input.Source)
+	validateTeams(c, input.Source.Teams)
+
+	sourceTeamNames := []string{}
+	for _, team := range input.Source.Teams {
+		sourceTeamNames = append(sourceTeamNames, team.Name)
+	}
+
+	for i, teamName := range input.Params.Teams {
+		if !concourse.StringContains(sourceTeamNames, teamName) {
+			c.add(
+				fmt.Sprintf("params.teams[%d]", i),
+				"team name '%s' not found in source team names: %v",
+				teamName,
+				sourceTeamNames,
+			)
+		}
+	}
+
+	for i, pattern := range input.Params.Pipelines {
+		if _, err := concourse.MatchPattern(pattern, ""); err != nil {
+			c.add(
+				fmt.Sprintf("params.pipelines[%d]", i),
+				"%s is not a valid pattern for pipelines[%d]: %v",
+				pattern,
+				i,
+				err,
+			)
+		}
+	}
+
+	switch input.Params.Layout {
+	case "", concourse.InLayoutFlat, concourse.InLayoutTeamDirs:
+	default:
+		c.add(
+			"params.layout",
+			"%s must be one of '%s' or '%s' if provided",
+			"layout",
+			concourse.InLayoutFlat,
+			concourse.InLayoutTeamDirs,
+		)
+	}
+
+	switch input.Params.Format {
+	case "", concourse.InFormatYAML, concourse.InFormatNormalizedYAML, concourse.InFormatJSON:
+	default:
+		c.add(
+			"params.format",
+			"%s must be one of '%s', '%s' or '%s' if provided",
+			"format",
+			concourse.InFormatYAML,
+			concourse.InFormatNormalizedYAML,
+			concourse.InFormatJSON,
+		)
+	}
+
+	if input.Params.Filename != "" {
+		if input.Params.Layout != "" {
+			c.add("params.filename", "%s must not be provided with %s", "filename", "layout")
+		}
+
+		if _, err := template.New("filename").Parse(input.Params.Filename); err != nil {
+			c.add("params.filename", "%s is not a valid template: %v", "filename", err)
+		}
 	}
 
-	return ValidateTeams(input.Source.Teams)
+	return c.err()
 }
@@ -0,0 +1,124 @@
+package validator_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/validator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateIn", func() {
+	var (
+		inRequest concourse.InRequest
+	)
+
+	BeforeEach(func() {
+		inRequest = concourse.InRequest{
+			Source: concourse.Source{
+				Target: "some target",
+				Teams: []concourse.Team{
+					{
+						Name:     "some team",
+						Username: "some user",
+						Password: "some password",
+					},
+				},
+			},
+			Params: concourse.InParams{
+				Layout: concourse.InLayoutTeamDirs,
+				Format: concourse.InFormatJSON,
+			},
+		}
+	})
+
+	It("returns without error", func() {
+		Expect(validator.ValidateIn(inRequest)).To(Succeed())
+	})
+
+	Context("when teams are provided", func() {
+		BeforeEach(func() {
+			inRequest.Params.Teams = []string{"some team"}
+		})
+
+		It("returns without error", func() {
+			Expect(validator.ValidateIn(inRequest)).To(Succeed())
+		})
+
+		Context("when a team is not in source", func() {
+			BeforeEach(func() {
+				inRequest.Params.Teams = []string{"some team", "other team"}
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateIn(inRequest)
+				Expect(err).To(MatchError("params.teams[1]: team name 'other team' not found in source team names: [some team]"))
+			})
+		})
+	})
+
+	Context("when a pipelines pattern is not valid", func() {
+		BeforeEach(func() {
+			inRequest.Params.Pipelines = []string{"ci-*", "/[/"}
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateIn(inRequest)
+			Expect(err).To(MatchError(ContainSubstring("params.pipelines[1]: /[/ is not a valid pattern for pipelines[1]")))
+		})
+	})
+
+	Context("when the layout is unknown", func() {
+		BeforeEach(func() {
+			inRequest.Params.Layout = "nested"
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateIn(inRequest)
+			Expect(err).To(MatchError("params.layout: layout must be one of 'flat' or 'team-dirs' if provided"))
+		})
+	})
+
+	Context("when the format is unknown", func() {
+		BeforeEach(func() {
+			inRequest.Params.Format = "toml"
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateIn(inRequest)
+			Expect(err).To(MatchError("params.format: format must be one of 'yaml', 'normalized-yaml' or 'json' if provided"))
+		})
+	})
+
+	Context("when a filename template is provided", func() {
+		BeforeEach(func() {
+			inRequest.Params.Layout = ""
+			inRequest.Params.Filename = "{{.Team}}/{{.Name}}.{{.Ext}}"
+		})
+
+		It("returns without error", func() {
+			Expect(validator.ValidateIn(inRequest)).To(Succeed())
+		})
+
+		Context("when a layout is also provided", func() {
+			BeforeEach(func() {
+				inRequest.Params.Layout = concourse.InLayoutFlat
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateIn(inRequest)
+				Expect(err).To(MatchError("params.filename: filename must not be provided with layout"))
+			})
+		})
+
+		Context("when it is not a valid template", func() {
+			BeforeEach(func() {
+				inRequest.Params.Filename = "{{.Team"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateIn(inRequest)
+				Expect(err).To(MatchError(ContainSubstring("params.filename: filename is not a valid template")))
+			})
+		})
+	})
+})
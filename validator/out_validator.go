@@ -7,20 +7,17 @@ import (
 )
 
 func ValidateOut(input concourse.OutRequest) error {
-	err := ValidateTeams(input.Source.Teams)
-	if err != nil {
-		return err
-	}
+	c := &collector{}
+
+	validateTarget(c, input.Source)
+	validateSource(c, input.Source)
+	validateTeams(c, input.Source.Teams)
 
 	sourceTeamNames := []string{}
 	for _, team := range input.Source.Teams {
 		sourceTeamNames = append(sourceTeamNames, team.Name)
 	}
 
-	if input.Source.Target == "" {
-		return fmt.Errorf("%s must be provided in source", "target")
-	}
-
 	var pipelinesFilePresent bool
 	var pipelinesPresent bool
 
@@ -33,7 +30,8 @@ func ValidateOut(input concourse.OutRequest) error {
 	}
 
 	if !(pipelinesPresent || pipelinesFilePresent) {
-		return fmt.Errorf(
+		c.add(
+			"params.pipelines",
 			"pipelines must be provided via either %s or %s",
 			"pipelines",
 			"pipelines_file",
@@ -41,40 +39,145 @@ func ValidateOut(input concourse.OutRequest) error {
 	}
 
 	if pipelinesPresent && pipelinesFilePresent {
-		return fmt.Errorf(
+		c.add(
+			"params.pipelines_file",
 			"pipelines must be provided via one of either %s or %s",
 			"pipelines",
 			"pipelines_file",
 		)
 	}
 
+	if input.Params.Concurrency < 0 {
+		c.add("params.concurrency", "%s must be positive if provided", "concurrency")
+	}
+
+	// Dependencies on pipelines expanded from a config_glob can only be
+	// resolved once it is expanded.
+	var globbed bool
+	for _, p := range input.Params.Pipelines {
+		if p.ConfigGlob != "" {
+			globbed = true
+		}
+	}
+
+	declared := map[string]bool{}
+
 	for i, p := range input.Params.Pipelines {
-		if p.Name == "" {
-			return fmt.Errorf("%s must be provided for pipeline[%d]", "name", i)
+		path := fmt.Sprintf("params.pipelines[%d]", i)
+
+		for j, key := range p.DependencyKeys() {
+			dependency := p.DependsOn[j]
+			dependencyPath := fmt.Sprintf("%s.depends_on[%d]", path, j)
+
+			if dependency == "" {
+				c.add(dependencyPath, "%s must be non-empty for pipeline[%d].depends_on[%d]", "dependency", i, j)
+			} else if !globbed && !declared[key] {
+				c.add(dependencyPath, "pipeline '%s' must be declared before pipeline[%d] which depends on it", dependency, i)
+			}
 		}
 
-		if p.ConfigFile == "" {
-			return fmt.Errorf("%s must be provided for pipeline[%d]", "config_file", i)
+		if p.ConfigGlob == "" {
+			declared[concourse.VersionKey(p.TeamName, p.Ref().String())] = true
 		}
 
-		if p.TeamName == "" {
-			return fmt.Errorf("%s must be provided for pipeline[%d]", "team", i)
+		if p.ConfigGlob != "" {
+			// The name and team are templates, which are validated once
+			// the pipeline is expanded.
+			if p.ConfigFile != "" {
+				c.add(path+".config_file", "%s must not be provided with %s for pipeline[%d]", "config_file", "config_glob", i)
+			}
+
+			if p.TeamName == "" {
+				c.add(path+".team", "%s must be provided for pipeline[%d]", "team", i)
+			}
+		} else {
+			if p.Name == "" {
+				c.add(path+".name", "%s must be provided for pipeline[%d]", "name", i)
+			}
+
+			if p.ConfigFile == "" {
+				c.add(path+".config_file", "%s must be provided for pipeline[%d]", "config_file", i)
+			}
+
+			if p.TeamName == "" {
+				c.add(path+".team", "%s must be provided for pipeline[%d]", "team", i)
+			} else if !concourse.StringContains(sourceTeamNames, p.TeamName) {
+				c.add(path+".team", "team name '%s' not found in source team names: %v", p.TeamName, sourceTeamNames)
+			}
+
+			if len(p.DirVarsFiles) > 0 {
+				c.add(path+".dir_vars_files", "%s must only be provided with %s for pipeline[%d]", "dir_vars_files", "config_glob", i)
+			}
 		}
 
-		if !stringContains(sourceTeamNames, p.TeamName) {
-			return fmt.Errorf("team name '%s' not found in source team names: %v", p.TeamName, sourceTeamNames)
+		if p.RenameFrom != "" {
+			if p.ConfigGlob != "" {
+				c.add(path+".rename_from", "%s must not be provided with %s for pipeline[%d]", "rename_from", "config_glob", i)
+			} else if len(p.InstanceVars) > 0 {
+				c.add(path+".rename_from", "%s must not be provided with %s for pipeline[%d]", "rename_from", "instance_vars", i)
+			} else if p.RenameFrom == p.Name {
+				c.add(path+".rename_from", "%s must differ from %s for pipeline[%d]", "rename_from", "name", i)
+			}
+		}
+
+		if p.Paused != nil && *p.Paused && p.Unpaused {
+			c.add(path+".unpaused", "%s must not be provided with %s for pipeline[%d]", "unpaused", "paused: true", i)
+		}
+
+		if p.Public != nil && !*p.Public && p.Exposed {
+			c.add(path+".exposed", "%s must not be provided with %s for pipeline[%d]", "exposed", "public: false", i)
+		}
+
+		if p.Archived && (p.Unpaused || (p.Paused != nil && !*p.Paused)) {
+			c.add(path+".archived", "%s pipelines can not be unpaused for pipeline[%d]", "archived", i)
+		}
+
+		for j, pattern := range p.SensitiveVars {
+			if _, err := concourse.MatchPattern(pattern, ""); err != nil {
+				c.add(
+					fmt.Sprintf("%s.sensitive_vars[%d]", path, j),
+					"%s is not a valid pattern for pipeline[%d].sensitive_vars[%d]: %v",
+					pattern,
+					i,
+					j,
+					err,
+				)
+			}
+		}
+
+		if p.Render != nil {
+			switch p.Render.Engine {
+			case concourse.RenderEngineTemplate, concourse.RenderEngineYtt, concourse.RenderEngineJsonnet:
+			default:
+				c.add(
+					path+".render.engine",
+					"%s must be one of '%s', '%s' or '%s' for pipeline[%d]",
+					"render.engine",
+					concourse.RenderEngineTemplate,
+					concourse.RenderEngineYtt,
+					concourse.RenderEngineJsonnet,
+					i,
+				)
+			}
+
+			for j, input := range p.Render.Inputs {
+				if input == "" {
+					c.add(fmt.Sprintf("%s.render.inputs[%d]", path, j), "%s must be non-empty for pipeline[%d].render.inputs[%d]", "render input", i, j)
+				}
+			}
 		}
 
 		// vars files can be nil as it is optional.
 		if p.VarsFiles != nil {
 			// However, if it is provided it must be non-empty
 			if len(p.VarsFiles) == 0 {
-				return fmt.Errorf("%s must be non-empty if provided for pipeline[%d]", "vars_files", i)
+				c.add(path+".vars_files", "%s must be non-empty if provided for pipeline[%d]", "vars_files", i)
 			}
 
 			for j, v := range p.VarsFiles {
 				if len(v) == 0 {
-					return fmt.Errorf(
+					c.add(
+						fmt.Sprintf("%s.vars_files[%d]", path, j),
 						"%s must be non-empty for pipeline[%d].vars_files[%d]",
 						"vars file",
 						i,
@@ -85,15 +188,35 @@ func ValidateOut(input concourse.OutRequest) error {
 		}
 	}
 
-	return nil
-}
+	for i, pr := range input.Params.Prune {
+		path := fmt.Sprintf("params.prune[%d]", i)
 
-func stringContains(slice []string, str string) bool {
-	for _, s := range slice {
-		if s == str {
-			return true
+		if pr.TeamName == "" {
+			c.add(path+".team", "%s must be provided for prune[%d]", "team", i)
+		} else if !concourse.StringContains(sourceTeamNames, pr.TeamName) {
+			c.add(path+".team", "team name '%s' not found in source team names: %v", pr.TeamName, sourceTeamNames)
+		}
+
+		switch pr.Action {
+		case "", concourse.PruneActionDestroy, concourse.PruneActionPause, concourse.PruneActionArchive:
+		default:
+			c.add(
+				path+".action",
+				"%s must be one of '%s', '%s' or '%s' for prune[%d]",
+				"action",
+				concourse.PruneActionDestroy,
+				concourse.PruneActionPause,
+				concourse.PruneActionArchive,
+				i,
+			)
+		}
+
+		for j, k := range pr.Keep {
+			if _, err := concourse.MatchGlob(k, ""); err != nil {
+				c.add(fmt.Sprintf("%s.keep[%d]", path, j), "%s is not a valid glob for prune[%d].keep[%d]: %v", k, i, j, err)
+			}
 		}
 	}
 
-	return false
+	return c.err()
 }
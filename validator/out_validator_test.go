@@ -48,6 +48,53 @@ var _ = Describe("ValidateOut", func() {
 		Expect(validator.ValidateOut(outRequest)).Should(Succeed())
 	})
 
+	Context("when there are several problems", func() {
+		BeforeEach(func() {
+			outRequest.Source.Target = ""
+			outRequest.Source.Teams[1].Password = ""
+			outRequest.Params.Pipelines = append(outRequest.Params.Pipelines,
+				concourse.Pipeline{
+					TeamName: "some team",
+					Name:     "p2",
+				},
+				concourse.Pipeline{
+					TeamName:   "some team",
+					Name:       "p3",
+					ConfigFile: "some config",
+				},
+				concourse.Pipeline{
+					TeamName:   "some team",
+					Name:       "p4",
+					ConfigFile: "some config",
+					VarsFiles:  []string{""},
+				},
+			)
+		})
+
+		It("returns every problem with its location", func() {
+			err := validator.ValidateOut(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			errs, ok := err.(validator.Errors)
+			Expect(ok).To(BeTrue())
+
+			var paths []string
+			for _, e := range errs {
+				paths = append(paths, e.Path)
+			}
+
+			Expect(paths).To(Equal([]string{
+				"source.target",
+				"source.teams[1].password",
+				"params.pipelines[1].config_file",
+				"params.pipelines[3].vars_files[0]",
+			}))
+
+			Expect(err.Error()).To(HavePrefix("4 validation errors:\n"))
+			Expect(err.Error()).To(ContainSubstring("\n  - params.pipelines[3].vars_files[0]: vars file must be non-empty"))
+		})
+	})
+
 	Context("when no team name is provided", func() {
 		BeforeEach(func() {
 			outRequest.Source.Teams[0].Name = ""
@@ -152,6 +199,262 @@ var _ = Describe("ValidateOut", func() {
 		})
 	})
 
+	Context("when a sensitive vars pattern is malformed", func() {
+		BeforeEach(func() {
+			outRequest.Params.Pipelines[0].SensitiveVars = []string{"/(/"}
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateOut(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*valid pattern.*pipeline.*sensitive_vars"))
+		})
+	})
+
+	Context("when a pipeline has a config glob", func() {
+		BeforeEach(func() {
+			outRequest.Params.Pipelines[0] = concourse.Pipeline{
+				ConfigGlob:   "pipelines/*/*.yml",
+				TeamName:     "{{index .Segments 1}}",
+				DirVarsFiles: []string{"vars.yml"},
+			}
+		})
+
+		It("does not require a name or config file, or the team to be in source", func() {
+			Expect(validator.ValidateOut(outRequest)).To(Succeed())
+		})
+
+		Context("when a config file is also provided", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].ConfigFile = "some config"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*config_file.*not.*config_glob"))
+			})
+		})
+	})
+
+	Context("when dir vars files are provided without a config glob", func() {
+		BeforeEach(func() {
+			outRequest.Params.Pipelines[0].DirVarsFiles = []string{"vars.yml"}
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateOut(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*dir_vars_files.*only.*config_glob"))
+		})
+	})
+
+	Context("when a pipeline is rendered", func() {
+		BeforeEach(func() {
+			outRequest.Params.Pipelines[0].Render = &concourse.Render{
+				Engine: concourse.RenderEngineYtt,
+				Inputs: []string{"some input"},
+			}
+		})
+
+		It("returns without error", func() {
+			Expect(validator.ValidateOut(outRequest)).To(Succeed())
+		})
+
+		Context("when the engine is unknown", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].Render.Engine = "cue"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*render.engine.*one of"))
+			})
+		})
+
+		Context("when an input is empty", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].Render.Inputs = []string{""}
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*render input.*non-empty"))
+			})
+		})
+	})
+
+	Context("when concurrency is negative", func() {
+		BeforeEach(func() {
+			outRequest.Params.Concurrency = -1
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateOut(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp("params.concurrency: concurrency must be positive"))
+		})
+	})
+
+	Context("when a pipeline depends on others", func() {
+		BeforeEach(func() {
+			outRequest.Params.Pipelines = append(outRequest.Params.Pipelines,
+				concourse.Pipeline{
+					TeamName:   "other team",
+					Name:       "p2",
+					ConfigFile: "some config",
+				},
+				concourse.Pipeline{
+					TeamName:   "some team",
+					Name:       "p3",
+					ConfigFile: "some config",
+					DependsOn:  []string{"p1", "other team/p2"},
+				},
+			)
+		})
+
+		It("returns without error", func() {
+			Expect(validator.ValidateOut(outRequest)).To(Succeed())
+		})
+
+		Context("when a dependency is declared later", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].DependsOn = []string{"p3"}
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(Equal("params.pipelines[0].depends_on[0]: pipeline 'p3' must be declared before pipeline[0] which depends on it"))
+			})
+		})
+
+		Context("when a dependency is in another team", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[2].DependsOn = []string{"p2"}
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp("pipeline 'p2' must be declared before"))
+			})
+		})
+
+		Context("when a dependency is an instanced pipeline", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].InstanceVars = map[string]interface{}{"branch": "main"}
+				outRequest.Params.Pipelines[2].DependsOn = []string{"p1/branch:main"}
+			})
+
+			It("returns without error", func() {
+				Expect(validator.ValidateOut(outRequest)).To(Succeed())
+			})
+
+			Context("when it refers to another instance", func() {
+				BeforeEach(func() {
+					outRequest.Params.Pipelines[2].DependsOn = []string{"p1/branch:other"}
+				})
+
+				It("returns an error", func() {
+					err := validator.ValidateOut(outRequest)
+					Expect(err).To(MatchError(ContainSubstring("pipeline 'p1/branch:other' must be declared before")))
+				})
+			})
+		})
+
+		Context("when a pipeline has a config glob", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[2].DependsOn = []string{"globbed"}
+				outRequest.Params.Pipelines = append(outRequest.Params.Pipelines, concourse.Pipeline{
+					TeamName:   "some team",
+					ConfigGlob: "pipelines/*.yml",
+				})
+			})
+
+			It("does not check dependencies until the glob is expanded", func() {
+				Expect(validator.ValidateOut(outRequest)).To(Succeed())
+			})
+		})
+	})
+
+	Context("when a pipeline is renamed", func() {
+		BeforeEach(func() {
+			outRequest.Params.Pipelines[0].RenameFrom = "old"
+		})
+
+		It("returns without error", func() {
+			Expect(validator.ValidateOut(outRequest)).To(Succeed())
+		})
+
+		Context("when it is renamed from its own name", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].RenameFrom = "p1"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(MatchError("params.pipelines[0].rename_from: rename_from must differ from name for pipeline[0]"))
+			})
+		})
+
+		Context("when it is instanced", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].InstanceVars = map[string]interface{}{"branch": "main"}
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(MatchError("params.pipelines[0].rename_from: rename_from must not be provided with instance_vars for pipeline[0]"))
+			})
+		})
+
+		Context("when it has a config glob", func() {
+			BeforeEach(func() {
+				outRequest.Params.Pipelines[0].Name = ""
+				outRequest.Params.Pipelines[0].ConfigFile = ""
+				outRequest.Params.Pipelines[0].ConfigGlob = "pipelines/*.yml"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(MatchError(ContainSubstring("rename_from must not be provided with config_glob")))
+			})
+		})
+	})
+
+	Context("when the state of a pipeline conflicts", func() {
+		BeforeEach(func() {
+			paused := true
+			public := false
+
+			outRequest.Params.Pipelines[0].Paused = &paused
+			outRequest.Params.Pipelines[0].Unpaused = true
+			outRequest.Params.Pipelines[0].Public = &public
+			outRequest.Params.Pipelines[0].Exposed = true
+			outRequest.Params.Pipelines[0].Archived = true
+		})
+
+		It("returns an error for each conflict", func() {
+			err := validator.ValidateOut(outRequest)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(ContainSubstring("unpaused must not be provided with paused: true"))
+			Expect(err.Error()).To(ContainSubstring("exposed must not be provided with public: false"))
+			Expect(err.Error()).To(ContainSubstring("archived pipelines can not be unpaused"))
+		})
+	})
+
 	Context("when team name is not provided in source", func() {
 		BeforeEach(func() {
 			outRequest.Params.Pipelines[0].TeamName = "not-supplied"
@@ -164,4 +467,72 @@ var _ = Describe("ValidateOut", func() {
 			Expect(err.Error()).To(MatchRegexp(".*name.*not found.*source.*"))
 		})
 	})
+
+	Context("when prune is provided", func() {
+		BeforeEach(func() {
+			outRequest.Params.Prune = []concourse.Prune{
+				{
+					TeamName: "some team",
+					Action:   "archive",
+					Keep:     []string{"legacy-*"},
+				},
+			}
+		})
+
+		It("returns without error", func() {
+			Expect(validator.ValidateOut(outRequest)).Should(Succeed())
+		})
+
+		Context("when no team is provided", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune[0].TeamName = ""
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*team.*provided.*prune.*0"))
+			})
+		})
+
+		Context("when the team is not provided in source", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune[0].TeamName = "not-supplied"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*name.*not found.*source.*"))
+			})
+		})
+
+		Context("when the action is unknown", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune[0].Action = "explode"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*action.*one of.*"))
+			})
+		})
+
+		Context("when a keep pattern is malformed", func() {
+			BeforeEach(func() {
+				outRequest.Params.Prune[0].Keep = []string{"["}
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateOut(outRequest)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*valid glob.*prune.*keep"))
+			})
+		})
+	})
 })
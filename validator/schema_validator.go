@@ -0,0 +1,133 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/schema"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidateSchema returns an error for every part of the JSON document b
+// which does not conform to s, such as a missing required field, a value of
+// the wrong type or a value which is not one of an enum. Paths in the
+// returned errors are prefixed by root.
+func ValidateSchema(b []byte, root string, s *schema.Schema) error {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err := decoder.Decode(&data)
+	if err != nil {
+		return err
+	}
+
+	c := &collector{}
+	validateSchema(c, root, data, s)
+	return c.err()
+}
+
+// ValidateYAMLSchema is ValidateSchema for YAML documents. Paths in the
+// returned errors are prefixed by root, e.g. the name of the file.
+func ValidateYAMLSchema(b []byte, root string, s *schema.Schema) error {
+	var data interface{}
+	err := yaml.Unmarshal(b, &data)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(concourse.JSONCompatible(data))
+	if err != nil {
+		return err
+	}
+
+	return ValidateSchema(j, root, s)
+}
+
+func validateSchema(c *collector, path string, data interface{}, s *schema.Schema) {
+	if data == nil {
+		// null is decoded as the zero value, as if the field were absent.
+		return
+	}
+
+	if s.Type != "" && !hasType(data, s.Type) {
+		c.add(path, "must be of type %s", s.Type)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		if str, ok := data.(string); ok && !concourse.StringContains(s.Enum, str) {
+			c.add(path, "must be one of '%s'", strings.Join(s.Enum, "', '"))
+		}
+	}
+
+	if s.Minimum != nil {
+		if n, ok := data.(json.Number); ok {
+			if f, err := n.Float64(); err == nil && f < float64(*s.Minimum) {
+				c.add(path, "must be at least %d", *s.Minimum)
+			}
+		}
+	}
+
+	switch d := data.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, found := d[name]; !found {
+				c.add(joinPath(path, name), "%s must be provided", name)
+			}
+		}
+
+		for _, k := range concourse.SortedKeys(d) {
+			if property, found := s.Properties[k]; found {
+				validateSchema(c, joinPath(path, k), d[k], property)
+				continue
+			}
+
+			switch additional := s.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					c.add(joinPath(path, k), "unknown field %s", k)
+				}
+			case *schema.Schema:
+				validateSchema(c, joinPath(path, k), d[k], additional)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range d {
+				validateSchema(c, fmt.Sprintf("%s[%d]", path, i), item, s.Items)
+			}
+		}
+	}
+}
+
+func hasType(data interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := data.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := data.([]interface{})
+		return ok
+	case "string":
+		_, ok := data.(string)
+		return ok
+	case "boolean":
+		_, ok := data.(bool)
+		return ok
+	case "integer":
+		n, ok := data.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "number":
+		_, ok := data.(json.Number)
+		return ok
+	default:
+		return true
+	}
+}
@@ -0,0 +1,144 @@
+package validator_test
+
+import (
+	"encoding/json"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/schema"
+	"github.com/concourse/concourse-pipeline-resource/validator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateSchema", func() {
+	var (
+		outRequest concourse.OutRequest
+	)
+
+	BeforeEach(func() {
+		outRequest = concourse.OutRequest{
+			Source: concourse.Source{
+				Target: "some target",
+				Teams: []concourse.Team{
+					{
+						Name:     "some team",
+						Username: "some username",
+						Password: "some password",
+					},
+				},
+			},
+			Params: concourse.OutParams{
+				Pipelines: []concourse.Pipeline{
+					{
+						TeamName:   "some team",
+						Name:       "p1",
+						ConfigFile: "some config",
+						Vars: map[string]interface{}{
+							"nested": map[string]interface{}{"replicas": 3},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	validate := func() error {
+		b, err := json.Marshal(outRequest)
+		Expect(err).NotTo(HaveOccurred())
+
+		return validator.ValidateSchema(b, "", schema.Of(outRequest))
+	}
+
+	It("accepts a valid request", func() {
+		Expect(validate()).To(Succeed())
+		Expect(validator.ValidateOut(outRequest)).To(Succeed())
+	})
+
+	It("returns every problem with its location", func() {
+		b := []byte(`{
+			"source": {"target": 1, "teams": [{"username": "u"}], "max_concurrency": -1},
+			"params": {"pipelines": [{"name": "p1", "team": "main", "config_file": "c", "unpause": true}]}
+		}`)
+
+		err := validator.ValidateSchema(b, "", schema.Of(concourse.OutRequest{}))
+		Expect(err).To(HaveOccurred())
+
+		Expect(err.(validator.Errors)).To(Equal(validator.Errors{
+			{Path: "params.pipelines[0].unpause", Message: "unknown field unpause"},
+			{Path: "source.max_concurrency", Message: "must be at least 0"},
+			{Path: "source.target", Message: "must be of type string"},
+			{Path: "source.teams[0].name", Message: "name must be provided"},
+		}))
+	})
+
+	It("accepts a concurrency of 0, as the validator does", func() {
+		b := []byte(`{
+			"source": {"target": "t", "teams": [{"name": "main"}]},
+			"params": {"pipelines": [{"name": "p1", "team": "main", "config_file": "c"}], "concurrency": 0}
+		}`)
+
+		Expect(validator.ValidateSchema(b, "", schema.Of(concourse.OutRequest{}))).To(Succeed())
+	})
+
+	Context("when a field is not one of its enum", func() {
+		BeforeEach(func() {
+			outRequest.Source.Client = "grpc"
+			outRequest.Params.Prune = []concourse.Prune{
+				{TeamName: "some team", Action: "delete"},
+			}
+		})
+
+		It("is rejected by both the schema and the validator", func() {
+			err := validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("source.client: must be one of 'fly', 'api'"))
+			Expect(err.Error()).To(ContainSubstring("params.prune[0].action: must be one of 'destroy', 'pause', 'archive'"))
+
+			err = validator.ValidateOut(outRequest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("source.client"))
+			Expect(err.Error()).To(ContainSubstring("params.prune[0].action"))
+		})
+	})
+
+	Context("when the log level and format are not one of their enums", func() {
+		BeforeEach(func() {
+			outRequest.Source.LogLevel = "verbose"
+			outRequest.Source.LogFormat = "xml"
+		})
+
+		It("is rejected by both the schema and the validator", func() {
+			err := validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.(validator.Errors)).To(HaveLen(2))
+
+			err = validator.ValidateOut(outRequest)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(validator.Errors)).To(HaveLen(2))
+		})
+	})
+})
+
+var _ = Describe("ValidateYAMLSchema", func() {
+	It("returns every problem with its location, prefixed by the root", func() {
+		b := []byte(`
+pipelines:
+- name: p1
+  team: main
+  config_file: c
+  vars_files: v.yml
+  vars: {replicas: 3}
+- name: p2
+  team: 1
+  config_file: c
+`)
+
+		err := validator.ValidateYAMLSchema(b, "pipelines.yml", schema.PipelinesFileSchema())
+		Expect(err).To(HaveOccurred())
+
+		Expect(err.(validator.Errors)).To(Equal(validator.Errors{
+			{Path: "pipelines.yml.pipelines[0].vars_files", Message: "must be of type array"},
+			{Path: "pipelines.yml.pipelines[1].team", Message: "must be of type string"},
+		}))
+	})
+})
@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/logger"
+)
+
+// ValidateSource validates the optional fields of source. Target and teams
+// are validated by the callers.
+func ValidateSource(source concourse.Source) error {
+	c := &collector{}
+	validateSource(c, source)
+	return c.err()
+}
+
+func validateSource(c *collector, source concourse.Source) {
+	if err := ValidateClient(source.Client); err != nil {
+		c.add("source.client", "%v", err)
+	}
+
+	if source.MaxConcurrency < 0 {
+		c.add("source.max_concurrency", "%s must not be negative if provided in source", "max_concurrency")
+	}
+
+	if _, err := logger.ParseLevel(source.LogLevel); err != nil {
+		c.add(
+			"source.log_level",
+			"%s must be one of 'debug', 'info', 'warn' or 'error' if provided in source",
+			"log_level",
+		)
+	}
+
+	if _, err := logger.ParseFormat(source.LogFormat); err != nil {
+		c.add(
+			"source.log_format",
+			"%s must be one of '%s' or '%s' if provided in source",
+			"log_format",
+			logger.FormatText,
+			logger.FormatJSON,
+		)
+	}
+
+	for i, pattern := range source.SensitiveVars {
+		if _, err := concourse.MatchPattern(pattern, ""); err != nil {
+			c.add(
+				fmt.Sprintf("source.sensitive_vars[%d]", i),
+				"%s is not a valid pattern for sensitive_vars[%d]: %v",
+				pattern,
+				i,
+				err,
+			)
+		}
+	}
+
+	if source.Archive != nil {
+		if source.Archive.URL == "" {
+			c.add("source.archive.url", "%s must be provided if %s is provided in source", "archive.url", "archive")
+		} else if u, err := url.Parse(source.Archive.URL); err != nil || (u.Scheme != "file" && u.Scheme != "http" && u.Scheme != "https") {
+			c.add("source.archive.url", "%s must be a file, http or https URL in source", "archive.url")
+		}
+	}
+}
+
+// validateTarget validates the target, which every request requires.
+func validateTarget(c *collector, source concourse.Source) {
+	if source.Target == "" {
+		c.add("source.target", "%s must be provided in source", "target")
+	}
+}
@@ -0,0 +1,129 @@
+package validator_test
+
+import (
+	"github.com/concourse/concourse-pipeline-resource/concourse"
+	"github.com/concourse/concourse-pipeline-resource/validator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateSource", func() {
+	var (
+		source concourse.Source
+	)
+
+	BeforeEach(func() {
+		source = concourse.Source{}
+	})
+
+	It("accepts no optional fields", func() {
+		Expect(validator.ValidateSource(source)).To(Succeed())
+	})
+
+	It("accepts the fly client", func() {
+		source.Client = "fly"
+		Expect(validator.ValidateSource(source)).To(Succeed())
+	})
+
+	It("accepts the api client", func() {
+		source.Client = "api"
+		Expect(validator.ValidateSource(source)).To(Succeed())
+	})
+
+	Context("when the client is unknown", func() {
+		BeforeEach(func() {
+			source.Client = "some-client"
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateSource(source)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*client.*one of"))
+		})
+	})
+
+	Context("when max concurrency is negative", func() {
+		BeforeEach(func() {
+			source.MaxConcurrency = -1
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateSource(source)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*max_concurrency.*negative"))
+		})
+	})
+
+	Context("when a sensitive vars pattern is malformed", func() {
+		BeforeEach(func() {
+			source.SensitiveVars = []string{"*-key", "/(/"}
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateSource(source)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*valid pattern.*sensitive_vars\\[1\\]"))
+		})
+	})
+
+	Context("when the log level is unknown", func() {
+		BeforeEach(func() {
+			source.LogLevel = "verbose"
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateSource(source)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*log_level.*one of"))
+		})
+	})
+
+	Context("when the log format is unknown", func() {
+		BeforeEach(func() {
+			source.LogFormat = "xml"
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateSource(source)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*log_format.*one of"))
+		})
+	})
+
+	Context("when an archive is provided", func() {
+		BeforeEach(func() {
+			source.Archive = &concourse.Archive{URL: "https://archive.example.com/pipelines"}
+		})
+
+		It("returns without error", func() {
+			Expect(validator.ValidateSource(source)).To(Succeed())
+		})
+
+		Context("when its URL is missing", func() {
+			BeforeEach(func() {
+				source.Archive.URL = ""
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateSource(source)
+				Expect(err).To(MatchError("source.archive.url: archive.url must be provided if archive is provided in source"))
+			})
+		})
+
+		Context("when its URL has an unsupported scheme", func() {
+			BeforeEach(func() {
+				source.Archive.URL = "s3://bucket/pipelines"
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateSource(source)
+				Expect(err).To(MatchError("source.archive.url: archive.url must be a file, http or https URL in source"))
+			})
+		})
+	})
+})
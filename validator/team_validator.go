@@ -7,23 +7,68 @@ import (
 )
 
 func ValidateTeams(teams []concourse.Team) error {
+	c := &collector{}
+	validateTeams(c, teams)
+	return c.err()
+}
+
+func validateTeams(c *collector, teams []concourse.Team) {
 	if teams == nil || len(teams) == 0 {
-		return fmt.Errorf("%s must be provided in source", "teams")
+		c.add("source.teams", "%s must be provided in source", "teams")
+		return
 	}
 
 	for i, team := range teams {
+		path := fmt.Sprintf("source.teams[%d]", i)
+
 		if team.Name == "" {
-			return fmt.Errorf("%s must be provided for team: %d", "name", i)
+			c.add(path+".name", "%s must be provided for team: %d", "name", i)
 		}
 
 		if team.Username == "" && team.Password != "" {
-			return fmt.Errorf("%s must be provided for team: %s", "username", team.Name)
+			c.add(path+".username", "%s must be provided for team: %s", "username", team.Name)
 		}
 
 		if team.Password == "" && team.Username != "" {
-			return fmt.Errorf("%s must be provided for team: %s", "password", team.Name)
+			c.add(path+".password", "%s must be provided for team: %s", "password", team.Name)
+		}
+
+		if team.ClientID == "" && team.ClientSecret != "" {
+			c.add(path+".client_id", "%s must be provided for team: %s", "client_id", team.Name)
+		}
+
+		if team.ClientSecret == "" && team.ClientID != "" {
+			c.add(path+".client_secret", "%s must be provided for team: %s", "client_secret", team.Name)
 		}
-	}
 
-	return nil
+		authModes := 0
+		for _, provided := range []bool{team.Username != "", team.Token != "", team.ClientID != ""} {
+			if provided {
+				authModes++
+			}
+		}
+
+		if authModes > 1 {
+			c.add(
+				path,
+				"only one of %s, %s or %s may be provided for team: %s",
+				"username and password",
+				"token",
+				"client_id and client_secret",
+				team.Name,
+			)
+		}
+
+		for j, p := range team.Pipelines.Include {
+			if _, err := concourse.MatchPattern(p, ""); err != nil {
+				c.add(fmt.Sprintf("%s.pipelines.include[%d]", path, j), "%s is not a valid pipelines pattern for team: %s: %v", p, team.Name, err)
+			}
+		}
+
+		for j, p := range team.Pipelines.Exclude {
+			if _, err := concourse.MatchPattern(p, ""); err != nil {
+				c.add(fmt.Sprintf("%s.pipelines.exclude[%d]", path, j), "%s is not a valid pipelines pattern for team: %s: %v", p, team.Name, err)
+			}
+		}
+	}
 }
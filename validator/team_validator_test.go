@@ -80,17 +80,96 @@ var _ = Describe("ValidateTeams", func() {
 		})
 	})
 
+	Context("when a token is provided instead of a username and password", func() {
+		BeforeEach(func() {
+			teams[0].Username = ""
+			teams[0].Password = ""
+			teams[0].Token = "some token"
+		})
+
+		It("does not throw an error", func() {
+			err := validator.ValidateTeams(teams)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when client credentials are provided instead of a username and password", func() {
+		BeforeEach(func() {
+			teams[0].Username = ""
+			teams[0].Password = ""
+			teams[0].ClientID = "some client"
+			teams[0].ClientSecret = "some secret"
+		})
+
+		It("does not throw an error", func() {
+			err := validator.ValidateTeams(teams)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when no client secret is provided", func() {
+			BeforeEach(func() {
+				teams[0].ClientSecret = ""
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateTeams(teams)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*client_secret.*provided.*team.*%s", "some team"))
+			})
+		})
+
+		Context("when no client id is provided", func() {
+			BeforeEach(func() {
+				teams[0].ClientID = ""
+			})
+
+			It("returns an error", func() {
+				err := validator.ValidateTeams(teams)
+				Expect(err).To(HaveOccurred())
+
+				Expect(err.Error()).To(MatchRegexp(".*client_id.*provided.*team.*%s", "some team"))
+			})
+		})
+	})
+
+	Context("when more than one authentication mode is provided", func() {
+		BeforeEach(func() {
+			teams[0].Token = "some token"
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateTeams(teams)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*only one of.*team.*%s", "some team"))
+		})
+	})
+
+	Context("when a pipelines pattern is invalid", func() {
+		BeforeEach(func() {
+			teams[0].Pipelines.Exclude = []string{"/(/"}
+		})
+
+		It("returns an error", func() {
+			err := validator.ValidateTeams(teams)
+			Expect(err).To(HaveOccurred())
+
+			Expect(err.Error()).To(MatchRegexp(".*valid pipelines pattern.*team.*%s", "some team"))
+		})
+	})
+
 	Context("when there are no teams", func() {
 		It("returns an error", func() {
 			err := validator.ValidateTeams([]concourse.Team{})
 			Expect(err).To(HaveOccurred())
 
-			Expect(err.Error()).To(Equal("teams must be provided in source"))
+			Expect(err.Error()).To(Equal("source.teams: teams must be provided in source"))
 
 			err = validator.ValidateTeams(nil)
 			Expect(err).To(HaveOccurred())
 
-			Expect(err.Error()).To(Equal("teams must be provided in source"))
+			Expect(err.Error()).To(Equal("source.teams: teams must be provided in source"))
 		})
 	})
 })